@@ -0,0 +1,165 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/accounts"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/collector"
+)
+
+// accountOptions select the profiles and accounts to scan, by default only
+// the account of the current credentials.
+type accountOptions struct {
+	profiles    []string
+	allProfiles bool
+	orgAccounts bool
+	accountIDs  string
+	roleName    string
+}
+
+// stringList is a flag that can be repeated, or given a comma separated list.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// loadCollectors returns a collector for each of the accounts to scan, for
+// each of the selected profiles. The profiles that can't be loaded are
+// skipped, unless none of them can.
+func loadCollectors(ctx context.Context, opts accountOptions) ([]*collector.Collector, error) {
+	profiles := opts.profiles
+	if opts.allProfiles {
+		var err error
+		profiles, err = accounts.ListProfiles(sharedConfigFiles())
+		if err != nil {
+			return nil, err
+		}
+		if len(profiles) == 0 {
+			return nil, fmt.Errorf("no profiles found in the shared config files")
+		}
+	}
+	if len(profiles) == 0 {
+		return loadProfileCollectors(ctx, "", opts)
+	}
+
+	var collectors []*collector.Collector
+	for _, profile := range profiles {
+		profileCollectors, err := loadProfileCollectors(ctx, profile, opts)
+		if err != nil {
+			if len(profiles) == 1 {
+				return nil, err
+			}
+			log.Printf("Skipping profile %s: %v", profile, err)
+			continue
+		}
+		collectors = append(collectors, profileCollectors...)
+	}
+	if len(collectors) == 0 {
+		return nil, fmt.Errorf("none of the %d profiles could be loaded", len(profiles))
+	}
+
+	log.Printf("Scanning %d accounts from %d profiles", len(collectors), len(profiles))
+	return collectors, nil
+}
+
+// sharedConfigFiles returns the shared config and credentials file paths,
+// honouring the same environment variables as the SDK.
+func sharedConfigFiles() (string, string) {
+	configFile := config.DefaultSharedConfigFilename()
+	if path := os.Getenv("AWS_CONFIG_FILE"); path != "" {
+		configFile = path
+	}
+	credentialsFile := config.DefaultSharedCredentialsFilename()
+	if path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); path != "" {
+		credentialsFile = path
+	}
+	return configFile, credentialsFile
+}
+
+// loadProfileCollectors returns a collector for each of the accounts to scan
+// using the given profile, or the default credentials if it's empty. The
+// other accounts are accessed by assuming roleName in them, while the account
+// of the profile's credentials uses them directly.
+func loadProfileCollectors(ctx context.Context, profile string, opts accountOptions) ([]*collector.Collector, error) {
+	var optFns []func(*config.LoadOptions) error
+	if profile != "" {
+		optFns = append(optFns, config.WithSharedConfigProfile(profile))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load SDK config: %v", err)
+	}
+
+	stsClient := sts.NewFromConfig(cfg)
+	identity, err := accounts.CallerIdentity(ctx, stsClient)
+	if err != nil {
+		return nil, err
+	}
+
+	var accountList []accounts.Account
+	switch {
+	case opts.orgAccounts:
+		accountList, err = accounts.ListOrganizationAccounts(ctx, organizations.NewFromConfig(cfg))
+	case opts.accountIDs != "":
+		accountList, err = accounts.ParseAccountIDs(opts.accountIDs)
+	default:
+		accountList = []accounts.Account{{ID: identity.AccountID}}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var collectors []*collector.Collector
+	for _, account := range accountList {
+		accountCfg := cfg
+		if account.ID != identity.AccountID {
+			roleARN := accounts.RoleARN(identity.Partition, account.ID, opts.roleName)
+			debug.Printf("Assuming %s for account %s", roleARN, account.ID)
+			accountCfg = accounts.AssumeRoleConfig(cfg, stsClient, roleARN)
+		}
+
+		c := collector.NewFromConfig(accountCfg)
+		c.Account = account.ID
+		c.Profile = profile
+		collectors = append(collectors, c)
+	}
+
+	if profile == "" {
+		log.Printf("Scanning %d accounts", len(collectors))
+	}
+	return collectors, nil
+}
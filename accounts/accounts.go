@@ -0,0 +1,140 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+// Package accounts finds the AWS accounts to scan and the credentials used to
+// access them.
+package accounts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+const (
+	// DefaultRoleName is the role created by AWS Organizations in the member
+	// accounts it creates.
+	DefaultRoleName = "OrganizationAccountAccessRole"
+
+	roleSessionName = "aws-ipv4-cost-viewer"
+)
+
+// OrganizationsAPI is the subset of the AWS Organizations API used to list
+// the member accounts.
+type OrganizationsAPI interface {
+	ListAccounts(ctx context.Context, params *organizations.ListAccountsInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsOutput, error)
+}
+
+// STSAPI is the subset of the STS API used to identify the caller and assume
+// roles in the other accounts.
+type STSAPI interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+	AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error)
+}
+
+type Account struct {
+	ID   string
+	Name string
+}
+
+// Identity is the account and partition of the credentials in use.
+type Identity struct {
+	AccountID string
+	Partition string
+}
+
+// CallerIdentity returns the account and partition of the caller.
+func CallerIdentity(ctx context.Context, client STSAPI) (Identity, error) {
+	resp, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to get caller identity: %w", err)
+	}
+
+	identity := Identity{AccountID: aws.ToString(resp.Account), Partition: "aws"}
+	if callerARN, err := arn.Parse(aws.ToString(resp.Arn)); err == nil {
+		identity.Partition = callerARN.Partition
+	}
+	return identity, nil
+}
+
+// ListOrganizationAccounts returns the active accounts of the organization.
+// It needs to be called from the management account or a delegated
+// administrator account.
+func ListOrganizationAccounts(ctx context.Context, client OrganizationsAPI) ([]Account, error) {
+	var accounts []Account
+
+	paginator := organizations.NewListAccountsPaginator(client, &organizations.ListAccountsInput{})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list organization accounts: %w", err)
+		}
+
+		for _, account := range resp.Accounts {
+			if account.Status != orgtypes.AccountStatusActive {
+				continue
+			}
+			accounts = append(accounts, Account{
+				ID:   aws.ToString(account.Id),
+				Name: aws.ToString(account.Name),
+			})
+		}
+	}
+	return accounts, nil
+}
+
+// ParseAccountIDs parses a comma separated list of account IDs.
+func ParseAccountIDs(list string) ([]Account, error) {
+	var accounts []Account
+	for _, id := range strings.Split(list, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if len(id) != 12 || strings.Trim(id, "0123456789") != "" {
+			return nil, fmt.Errorf("invalid account ID %q", id)
+		}
+		accounts = append(accounts, Account{ID: id})
+	}
+	return accounts, nil
+}
+
+// RoleARN returns the ARN of the role with the given name in an account.
+func RoleARN(partition, accountID, roleName string) string {
+	return arn.ARN{
+		Partition: partition,
+		Service:   "iam",
+		AccountID: accountID,
+		Resource:  "role/" + roleName,
+	}.String()
+}
+
+// AssumeRoleConfig returns a copy of cfg using the credentials of roleARN,
+// assumed through the given STS client and refreshed when they expire.
+func AssumeRoleConfig(cfg aws.Config, client stscreds.AssumeRoleAPIClient, roleARN string) aws.Config {
+	assumed := cfg.Copy()
+	assumed.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(client, roleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = roleSessionName
+	}))
+	return assumed
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package accounts
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+)
+
+type fakeOrganizations struct {
+	pages [][]orgtypes.Account
+}
+
+func (f *fakeOrganizations) ListAccounts(ctx context.Context, params *organizations.ListAccountsInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsOutput, error) {
+	index := 0
+	if params.NextToken != nil {
+		index = int(aws.ToString(params.NextToken)[0] - '0')
+	}
+
+	out := &organizations.ListAccountsOutput{Accounts: f.pages[index]}
+	if index+1 < len(f.pages) {
+		out.NextToken = aws.String(string(rune('0' + index + 1)))
+	}
+	return out, nil
+}
+
+func TestListOrganizationAccounts(t *testing.T) {
+	client := &fakeOrganizations{pages: [][]orgtypes.Account{
+		{
+			{Id: aws.String("111111111111"), Name: aws.String("management"), Status: orgtypes.AccountStatusActive},
+			{Id: aws.String("222222222222"), Name: aws.String("closed"), Status: orgtypes.AccountStatusSuspended},
+		},
+		{
+			{Id: aws.String("333333333333"), Name: aws.String("workload"), Status: orgtypes.AccountStatusActive},
+		},
+	}}
+
+	got, err := ListOrganizationAccounts(context.Background(), client)
+	if err != nil {
+		t.Fatalf("ListOrganizationAccounts() error = %v", err)
+	}
+
+	want := []Account{
+		{ID: "111111111111", Name: "management"},
+		{ID: "333333333333", Name: "workload"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListOrganizationAccounts() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseAccountIDs(t *testing.T) {
+	got, err := ParseAccountIDs(" 111111111111, 222222222222,")
+	if err != nil {
+		t.Fatalf("ParseAccountIDs() error = %v", err)
+	}
+	want := []Account{{ID: "111111111111"}, {ID: "222222222222"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseAccountIDs() = %+v, want %+v", got, want)
+	}
+
+	for _, invalid := range []string{"12345", "11111111111a"} {
+		if _, err := ParseAccountIDs(invalid); err == nil {
+			t.Errorf("ParseAccountIDs(%q) succeeded, want an error", invalid)
+		}
+	}
+}
+
+func TestRoleARN(t *testing.T) {
+	got := RoleARN("aws-us-gov", "111111111111", DefaultRoleName)
+	want := "arn:aws-us-gov:iam::111111111111:role/OrganizationAccountAccessRole"
+	if got != want {
+		t.Errorf("RoleARN() = %s, want %s", got, want)
+	}
+}
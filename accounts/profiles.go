@@ -0,0 +1,90 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package accounts
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ListProfiles returns the names of all the profiles defined in the shared
+// config and credentials files, sorted and without duplicates. Missing files
+// are ignored.
+func ListProfiles(configFile, credentialsFile string) ([]string, error) {
+	seen := map[string]bool{}
+	for _, file := range []struct {
+		path   string
+		config bool
+	}{
+		{configFile, true},
+		{credentialsFile, false},
+	} {
+		f, err := os.Open(file.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", file.path, err)
+		}
+
+		names, err := parseProfileNames(f, file.config)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.path, err)
+		}
+		for _, name := range names {
+			seen[name] = true
+		}
+	}
+
+	profiles := make([]string, 0, len(seen))
+	for name := range seen {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+	return profiles, nil
+}
+
+// parseProfileNames returns the profile names from the section headers of a
+// shared config file, where they're written as [profile name] except for the
+// default one, or of a shared credentials file, where they're written as
+// [name]. Other config sections, like sso-session, are skipped.
+func parseProfileNames(r io.Reader, configFile bool) ([]string, error) {
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+			continue
+		}
+		section := strings.Fields(strings.Trim(line, "[]"))
+
+		switch {
+		case len(section) == 1 && (!configFile || section[0] == "default"):
+			names = append(names, section[0])
+		case len(section) == 2 && configFile && section[0] == "profile":
+			names = append(names, section[1])
+		}
+	}
+	return names, scanner.Err()
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package accounts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestListProfiles(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config")
+	credentialsFile := filepath.Join(dir, "credentials")
+
+	config := `[default]
+region = us-east-1
+
+[profile dev]
+sso_session = corp
+
+[sso-session corp]
+sso_region = us-east-1
+
+[ profile prod ]
+role_arn = arn:aws:iam::111111111111:role/admin
+`
+	credentials := `[default]
+aws_access_key_id = AKIA
+
+[legacy]
+aws_access_key_id = AKIA
+`
+	if err := os.WriteFile(configFile, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(credentialsFile, []byte(credentials), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ListProfiles(configFile, credentialsFile)
+	if err != nil {
+		t.Fatalf("ListProfiles() error = %v", err)
+	}
+	want := []string{"default", "dev", "legacy", "prod"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListProfiles() = %v, want %v", got, want)
+	}
+
+	got, err = ListProfiles(filepath.Join(dir, "missing"), credentialsFile)
+	if err != nil {
+		t.Fatalf("ListProfiles() with a missing config file error = %v", err)
+	}
+	want = []string{"default", "legacy"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListProfiles() with a missing config file = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/globalaccelerator"
+	"github.com/aws/aws-sdk-go-v2/service/opensearch"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/redshift"
+)
+
+// EC2API is the subset of the EC2 API used by the collectors.
+type EC2API interface {
+	DescribeRegions(ctx context.Context, params *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error)
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	DescribeNetworkInterfaces(ctx context.Context, params *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error)
+	DescribeAddresses(ctx context.Context, params *ec2.DescribeAddressesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeAddressesOutput, error)
+	DescribeNatGateways(ctx context.Context, params *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error)
+	DescribeVpnConnections(ctx context.Context, params *ec2.DescribeVpnConnectionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpnConnectionsOutput, error)
+	DescribeClientVpnEndpoints(ctx context.Context, params *ec2.DescribeClientVpnEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeClientVpnEndpointsOutput, error)
+	DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+	DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error)
+	ModifySubnetAttribute(ctx context.Context, params *ec2.ModifySubnetAttributeInput, optFns ...func(*ec2.Options)) (*ec2.ModifySubnetAttributeOutput, error)
+}
+
+// ELBAPI is the subset of the Classic ELB API used by the collectors.
+type ELBAPI interface {
+	DescribeLoadBalancers(ctx context.Context, params *elb.DescribeLoadBalancersInput, optFns ...func(*elb.Options)) (*elb.DescribeLoadBalancersOutput, error)
+}
+
+// ELBv2API is the subset of the ALB/NLB API used by the collectors.
+type ELBv2API interface {
+	DescribeLoadBalancers(ctx context.Context, params *elbv2.DescribeLoadBalancersInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeLoadBalancersOutput, error)
+	DescribeTargetGroups(ctx context.Context, params *elbv2.DescribeTargetGroupsInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeTargetGroupsOutput, error)
+}
+
+// CloudWatchAPI is the subset of the CloudWatch API used by the collectors.
+type CloudWatchAPI interface {
+	GetMetricData(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error)
+}
+
+// GlobalAcceleratorAPI is the subset of the Global Accelerator API used by the
+// collectors.
+type GlobalAcceleratorAPI interface {
+	ListAccelerators(ctx context.Context, params *globalaccelerator.ListAcceleratorsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListAcceleratorsOutput, error)
+	ListCustomRoutingAccelerators(ctx context.Context, params *globalaccelerator.ListCustomRoutingAcceleratorsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListCustomRoutingAcceleratorsOutput, error)
+	ListListeners(ctx context.Context, params *globalaccelerator.ListListenersInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListListenersOutput, error)
+	ListCustomRoutingListeners(ctx context.Context, params *globalaccelerator.ListCustomRoutingListenersInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListCustomRoutingListenersOutput, error)
+	ListEndpointGroups(ctx context.Context, params *globalaccelerator.ListEndpointGroupsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListEndpointGroupsOutput, error)
+	ListCustomRoutingEndpointGroups(ctx context.Context, params *globalaccelerator.ListCustomRoutingEndpointGroupsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListCustomRoutingEndpointGroupsOutput, error)
+	ListByoipCidrs(ctx context.Context, params *globalaccelerator.ListByoipCidrsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListByoipCidrsOutput, error)
+}
+
+// RDSAPI is the subset of the RDS API used by the collectors.
+type RDSAPI interface {
+	DescribeDBInstances(ctx context.Context, params *rds.DescribeDBInstancesInput, optFns ...func(*rds.Options)) (*rds.DescribeDBInstancesOutput, error)
+	DescribeDBClusters(ctx context.Context, params *rds.DescribeDBClustersInput, optFns ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error)
+}
+
+// RedshiftAPI is the subset of the Redshift API used by the collectors.
+type RedshiftAPI interface {
+	DescribeClusters(ctx context.Context, params *redshift.DescribeClustersInput, optFns ...func(*redshift.Options)) (*redshift.DescribeClustersOutput, error)
+}
+
+// OpenSearchAPI is the subset of the OpenSearch Service API used by the
+// collectors.
+type OpenSearchAPI interface {
+	ListDomainNames(ctx context.Context, params *opensearch.ListDomainNamesInput, optFns ...func(*opensearch.Options)) (*opensearch.ListDomainNamesOutput, error)
+	DescribeDomains(ctx context.Context, params *opensearch.DescribeDomainsInput, optFns ...func(*opensearch.Options)) (*opensearch.DescribeDomainsOutput, error)
+}
+
+// ClientFactory creates the regional clients used by the collectors. An empty
+// region means the default region of the factory. Global Accelerator is a
+// global service, always called in its home region.
+type ClientFactory interface {
+	EC2(region string) EC2API
+	ELB(region string) ELBAPI
+	ELBv2(region string) ELBv2API
+	CloudWatch(region string) CloudWatchAPI
+	GlobalAccelerator() GlobalAcceleratorAPI
+	RDS(region string) RDSAPI
+	Redshift(region string) RedshiftAPI
+	OpenSearch(region string) OpenSearchAPI
+}
+
+type configClientFactory struct {
+	cfg aws.Config
+}
+
+// NewClientFactory returns a ClientFactory creating the AWS SDK clients from
+// the given config.
+func NewClientFactory(cfg aws.Config) ClientFactory {
+	return &configClientFactory{cfg: cfg}
+}
+
+func (f *configClientFactory) EC2(region string) EC2API {
+	return ec2.NewFromConfig(f.cfg, func(o *ec2.Options) {
+		if region != "" {
+			o.Region = region
+		}
+	})
+}
+
+func (f *configClientFactory) ELB(region string) ELBAPI {
+	return elb.NewFromConfig(f.cfg, func(o *elb.Options) {
+		if region != "" {
+			o.Region = region
+		}
+	})
+}
+
+func (f *configClientFactory) ELBv2(region string) ELBv2API {
+	return elbv2.NewFromConfig(f.cfg, func(o *elbv2.Options) {
+		if region != "" {
+			o.Region = region
+		}
+	})
+}
+
+func (f *configClientFactory) CloudWatch(region string) CloudWatchAPI {
+	return cloudwatch.NewFromConfig(f.cfg, func(o *cloudwatch.Options) {
+		if region != "" {
+			o.Region = region
+		}
+	})
+}
+
+func (f *configClientFactory) GlobalAccelerator() GlobalAcceleratorAPI {
+	return globalaccelerator.NewFromConfig(f.cfg, func(o *globalaccelerator.Options) {
+		o.Region = GlobalAcceleratorRegion
+	})
+}
+
+func (f *configClientFactory) RDS(region string) RDSAPI {
+	return rds.NewFromConfig(f.cfg, func(o *rds.Options) {
+		if region != "" {
+			o.Region = region
+		}
+	})
+}
+
+func (f *configClientFactory) Redshift(region string) RedshiftAPI {
+	return redshift.NewFromConfig(f.cfg, func(o *redshift.Options) {
+		if region != "" {
+			o.Region = region
+		}
+	})
+}
+
+func (f *configClientFactory) OpenSearch(region string) OpenSearchAPI {
+	return opensearch.NewFromConfig(f.cfg, func(o *opensearch.Options) {
+		if region != "" {
+			o.Region = region
+		}
+	})
+}
@@ -0,0 +1,237 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+// Package collector fetches the AWS resources that have public IPv4 addresses
+// attached and computes their costs.
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/pricing"
+)
+
+var debug = log.New(io.Discard, "", 0) // No-op logger
+
+// SetDebugLogger sets the logger used for the debug output of the collectors.
+func SetDebugLogger(logger *log.Logger) {
+	debug = logger
+}
+
+// Collector fetches resources using the regional clients created by its
+// ClientFactory.
+type Collector struct {
+	// Account is the AWS account ID added to all the data fetched by the
+	// collector, to tell apart the data of multiple accounts.
+	Account string
+	// Profile is the shared config profile the collector's credentials were
+	// loaded from, if any, added to all the data fetched by the collector.
+	Profile string
+	// Pricing computes the costs of the public IPs found, using the AWS list
+	// price by default.
+	Pricing *pricing.Model
+	// LoadBalancerDNSFallback resolves the DNS names of the internet facing
+	// load balancers whose public IPs can't be found from their ENIs.
+	LoadBalancerDNSFallback bool
+	// Lookback is the window the load balancer metrics are fetched for,
+	// DefaultLookback by default.
+	Lookback time.Duration
+
+	clients ClientFactory
+
+	mu        sync.Mutex
+	scanStats map[scanKey]*ScanStat
+	// shared are the regional results used by several collectors during a
+	// scan, until ResetCache is called
+	shared map[scanKey]*sharedResult
+}
+
+// New returns a Collector using the given client factory.
+func New(clients ClientFactory) *Collector {
+	return &Collector{
+		Pricing:   pricing.Default(),
+		Lookback:  DefaultLookback,
+		clients:   clients,
+		scanStats: map[scanKey]*ScanStat{},
+		shared:    map[scanKey]*sharedResult{},
+	}
+}
+
+// NewFromConfig returns a Collector using the AWS SDK clients created from the
+// given config.
+func NewFromConfig(cfg aws.Config) *Collector {
+	return New(NewClientFactory(cfg))
+}
+
+// RegionError is returned when a collector fails to fetch resources from one
+// of the regions. The results from the other regions are still returned.
+type RegionError struct {
+	Account string
+	Region  string
+	Err     error
+}
+
+func (e *RegionError) Error() string {
+	if e.Account != "" {
+		return fmt.Sprintf("account %s region %s: %v", e.Account, e.Region, e.Err)
+	}
+	return fmt.Sprintf("region %s: %v", e.Region, e.Err)
+}
+
+func (e *RegionError) Unwrap() error {
+	return e.Err
+}
+
+// ScanStat counts the API result pages and the items they contained for an
+// operation in a region, so that the totals can be checked for completeness.
+type ScanStat struct {
+	Profile   string `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Account   string `json:"account" yaml:"account"`
+	Region    string `json:"region" yaml:"region"`
+	Operation string `json:"operation" yaml:"operation"`
+	Pages     int    `json:"pages" yaml:"pages"`
+	Items     int    `json:"items" yaml:"items"`
+}
+
+type scanKey struct {
+	region    string
+	operation string
+}
+
+func (c *Collector) recordScan(region, operation string, pages, items int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := scanKey{region: region, operation: operation}
+	stat, ok := c.scanStats[key]
+	if !ok {
+		stat = &ScanStat{Profile: c.Profile, Account: c.Account, Region: region, Operation: operation}
+		c.scanStats[key] = stat
+	}
+	stat.Pages += pages
+	stat.Items += items
+}
+
+// ScanStats returns the pages and items scanned so far by the collector,
+// sorted by region and operation.
+func (c *Collector) ScanStats() []ScanStat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := make([]ScanStat, 0, len(c.scanStats))
+	for _, stat := range c.scanStats {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Region != stats[j].Region {
+			return stats[i].Region < stats[j].Region
+		}
+		return stats[i].Operation < stats[j].Operation
+	})
+	return stats
+}
+
+// ResetScanStats forgets the pages and items scanned so far, before the
+// collector is run again.
+func (c *Collector) ResetScanStats() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.scanStats = map[scanKey]*ScanStat{}
+}
+
+// ResetCache forgets the regional results shared by the collectors, so that
+// the next scan fetches them again. It must be called before each scan.
+func (c *Collector) ResetCache() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.shared = map[scanKey]*sharedResult{}
+}
+
+// sharedResult is the result of an operation in a region, fetched once for
+// all the collectors that need it. done is closed once it's fetched.
+type sharedResult struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// fetchShared calls fetch only once for the region and operation until the
+// cache is reset, and returns its result to all the callers, so that the
+// collectors sharing the EIPs, the NAT gateways or the subnets of a region make
+// their requests and record their scan stats once. The callers waiting for a
+// fetch that fails get its error, but the error isn't kept, so the next
+// callers try again. The result must not be modified.
+func fetchShared[T any](c *Collector, region, operation string, fetch func() (T, error)) (T, error) {
+	key := scanKey{region: region, operation: operation}
+	c.mu.Lock()
+	result, ok := c.shared[key]
+	if !ok {
+		result = &sharedResult{done: make(chan struct{})}
+		c.shared[key] = result
+	}
+	c.mu.Unlock()
+
+	if ok {
+		<-result.done
+	} else {
+		result.value, result.err = fetch()
+		if result.err != nil {
+			c.mu.Lock()
+			if c.shared[key] == result {
+				delete(c.shared, key)
+			}
+			c.mu.Unlock()
+		}
+		close(result.done)
+	}
+	value, _ := result.value.(T)
+	return value, result.err
+}
+
+// forgetShared drops the shared result of the operation in the region, once
+// it's out of date.
+func (c *Collector) forgetShared(region, operation string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.shared, scanKey{region: region, operation: operation})
+}
+
+// FetchRegions returns the names of the regions enabled in the account.
+func (c *Collector) FetchRegions(ctx context.Context) ([]string, error) {
+	resp, err := c.clients.EC2("").DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe regions: %w", err)
+	}
+
+	c.recordScan("", "DescribeRegions", 1, len(resp.Regions))
+
+	var regions []string
+	for _, region := range resp.Regions {
+		regions = append(regions, aws.ToString(region.RegionName))
+	}
+	return regions, nil
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestFetchRegions(t *testing.T) {
+	c := New(&fakeClients{ec2: map[string]*fakeEC2{
+		"": {regions: []types.Region{
+			{RegionName: aws.String("us-east-1")},
+			{RegionName: aws.String("eu-west-1")},
+		}},
+	}})
+
+	regions, err := c.FetchRegions(context.Background())
+	if err != nil {
+		t.Fatalf("FetchRegions() error = %v", err)
+	}
+
+	want := []string{"us-east-1", "eu-west-1"}
+	if !reflect.DeepEqual(regions, want) {
+		t.Errorf("FetchRegions() = %v, want %v", regions, want)
+	}
+}
+
+func TestRegionErrorsAreJoined(t *testing.T) {
+	apiErr := errors.New("access denied")
+	c := New(&fakeClients{ec2: map[string]*fakeEC2{
+		"eu-west-1": {err: apiErr},
+		"us-east-1": {networkInterfaces: []types.NetworkInterface{publicENI("eni-1", "1.1.1.1")}},
+	}})
+
+	enis, err := c.FetchAllENIs(context.Background(), []string{"us-east-1", "eu-west-1"})
+	if len(enis) != 1 {
+		t.Errorf("FetchAllENIs() returned %d ENIs, want the 1 from the region without errors", len(enis))
+	}
+
+	var regionErr *RegionError
+	if !errors.As(err, &regionErr) {
+		t.Fatalf("FetchAllENIs() error = %v, want a *RegionError", err)
+	}
+	if regionErr.Region != "eu-west-1" {
+		t.Errorf("RegionError.Region = %s, want eu-west-1", regionErr.Region)
+	}
+	if !errors.Is(err, apiErr) {
+		t.Errorf("FetchAllENIs() error = %v, want it to wrap %v", err, apiErr)
+	}
+}
+
+func TestPaginationAndScanStats(t *testing.T) {
+	var enis []types.NetworkInterface
+	for i := 0; i < 5; i++ {
+		enis = append(enis, publicENI(fmt.Sprintf("eni-%d", i), fmt.Sprintf("1.1.1.%d", i)))
+	}
+	enis = append(enis, types.NetworkInterface{NetworkInterfaceId: aws.String("eni-private")})
+
+	c := New(&fakeClients{ec2: map[string]*fakeEC2{
+		"us-east-1": {pageSize: 2, networkInterfaces: enis},
+	}})
+
+	got, err := c.FetchAllENIs(context.Background(), []string{"us-east-1"})
+	if err != nil {
+		t.Fatalf("FetchAllENIs() error = %v", err)
+	}
+	if len(got) != 5 {
+		t.Errorf("FetchAllENIs() returned %d ENIs, want all 5 public ones across pages", len(got))
+	}
+
+	// The EIPs tell the BYOIP addresses apart
+	want := []ScanStat{
+		{Region: "us-east-1", Operation: "DescribeAddresses", Pages: 1, Items: 0},
+		{Region: "us-east-1", Operation: "DescribeNetworkInterfaces", Pages: 3, Items: 6},
+	}
+	if stats := c.ScanStats(); !reflect.DeepEqual(stats, want) {
+		t.Errorf("ScanStats() = %+v, want %+v", stats, want)
+	}
+}
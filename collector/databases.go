@@ -0,0 +1,568 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/opensearch"
+	opensearchtypes "github.com/aws/aws-sdk-go-v2/service/opensearch/types"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdstypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/aws/aws-sdk-go-v2/service/redshift"
+	redshifttypes "github.com/aws/aws-sdk-go-v2/service/redshift/types"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/pricing"
+)
+
+// DatabaseInfo is a publicly accessible RDS DB instance, Redshift cluster or
+// OpenSearch domain, with the ENIs and public IPs it's reachable through. The
+// public OpenSearch domains are served from IPs of the service, outside of the
+// account, so they have neither.
+type DatabaseInfo struct {
+	Profile          string   `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Account          string   `json:"account" yaml:"account"`
+	Region           string   `json:"region" yaml:"region"`
+	Service          string   `json:"service" yaml:"service"`
+	ID               string   `json:"id" yaml:"id"`
+	Cluster          string   `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	Engine           string   `json:"engine" yaml:"engine"`
+	Status           string   `json:"status" yaml:"status"`
+	Endpoint         string   `json:"endpoint" yaml:"endpoint"`
+	VPCID            string   `json:"vpc_id" yaml:"vpc_id"`
+	SecurityGroupIDs []string `json:"security_group_ids" yaml:"security_group_ids"`
+	ENIIDs           []string `json:"eni_ids" yaml:"eni_ids"`
+	IPCount          int      `json:"ip_count" yaml:"ip_count"`
+	PublicIPs        []string `json:"public_ips" yaml:"public_ips"`
+	// UnchargedIPCount are the public IPs that aren't owned by Amazon, such
+	// as BYOIP EIPs, which are left out of the costs
+	UnchargedIPCount int     `json:"uncharged_ip_count" yaml:"uncharged_ip_count"`
+	Cost             float64 `json:"cost" yaml:"cost"`
+
+	// PublicIngressGroups are the security groups allowing inbound traffic
+	// from public IPs. PrivateCandidate is set for the databases with public
+	// IPs and none of them, which aren't reachable from the internet anyway and
+	// could be made private, with PrivateNote telling the monthly savings.
+	PublicIngressGroups []string `json:"public_ingress_groups" yaml:"public_ingress_groups"`
+	PrivateCandidate    bool     `json:"private_candidate" yaml:"private_candidate"`
+	PrivateNote         string   `json:"private_note,omitempty" yaml:"private_note,omitempty"`
+}
+
+const (
+	EngineRedshift = "redshift"
+
+	databasePageSize = 100
+	// openSearchDescribedDomains is the number of domains DescribeDomains
+	// accepts at once.
+	openSearchDescribedDomains = 5
+)
+
+// privatePrefixes are the private and shared IPv4 ranges, and the unique
+// local IPv6 range, the inbound rules from anything else allow access from the
+// internet.
+var privatePrefixes = []netip.Prefix{
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("fc00::/7"),
+}
+
+// FetchAllDatabases returns the publicly accessible RDS DB instances, Redshift
+// clusters and OpenSearch domains from all the given regions. The scan is
+// best-effort: the databases of the services and regions that could be
+// scanned are still returned along with the errors of the others.
+func (c *Collector) FetchAllDatabases(ctx context.Context, regions []string) ([]DatabaseInfo, error) {
+	var allDatabases []DatabaseInfo
+	var errs []error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, region := range regions {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+
+			databases, err := c.fetchRegionDatabases(ctx, region)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, &RegionError{Account: c.Account, Region: region, Err: err})
+			}
+			allDatabases = append(allDatabases, databases...)
+		}(region)
+	}
+	wg.Wait()
+
+	return allDatabases, errors.Join(errs...)
+}
+
+func (c *Collector) fetchRegionDatabases(ctx context.Context, region string) ([]DatabaseInfo, error) {
+	var errs []error
+	// The unmatched ENIs of a service are only reported once it's listed
+	listed := map[string]bool{}
+	instances, err := c.fetchPublicRDSInstances(ctx, region)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	listed[ServiceRDS] = err == nil
+	clusters, err := c.fetchPublicRedshiftClusters(ctx, region)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	listed[ServiceRedshift] = err == nil
+	domains, engineTypes, err := c.fetchPublicOpenSearchDomains(ctx, region)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	var infos []DatabaseInfo
+	rdsSubnets := map[int][]string{}
+	for _, instance := range instances {
+		info := DatabaseInfo{
+			Profile: c.Profile,
+			Account: c.Account,
+			Region:  region,
+			Service: ServiceRDS,
+			ID:      aws.ToString(instance.DBInstanceIdentifier),
+			Cluster: aws.ToString(instance.DBClusterIdentifier),
+			Engine:  aws.ToString(instance.Engine),
+			Status:  aws.ToString(instance.DBInstanceStatus),
+		}
+		if instance.Endpoint != nil {
+			info.Endpoint = aws.ToString(instance.Endpoint.Address)
+		}
+		for _, group := range instance.VpcSecurityGroups {
+			info.SecurityGroupIDs = append(info.SecurityGroupIDs, aws.ToString(group.VpcSecurityGroupId))
+		}
+		if subnetGroup := instance.DBSubnetGroup; subnetGroup != nil {
+			info.VPCID = aws.ToString(subnetGroup.VpcId)
+			for _, subnet := range subnetGroup.Subnets {
+				rdsSubnets[len(infos)] = append(rdsSubnets[len(infos)], aws.ToString(subnet.SubnetIdentifier))
+			}
+		}
+		infos = append(infos, info)
+	}
+
+	for _, cluster := range clusters {
+		info := DatabaseInfo{
+			Profile: c.Profile,
+			Account: c.Account,
+			Region:  region,
+			Service: ServiceRedshift,
+			ID:      aws.ToString(cluster.ClusterIdentifier),
+			Engine:  EngineRedshift,
+			Status:  aws.ToString(cluster.ClusterStatus),
+			VPCID:   aws.ToString(cluster.VpcId),
+		}
+		if cluster.Endpoint != nil {
+			info.Endpoint = aws.ToString(cluster.Endpoint.Address)
+		}
+		for _, group := range cluster.VpcSecurityGroups {
+			info.SecurityGroupIDs = append(info.SecurityGroupIDs, aws.ToString(group.VpcSecurityGroupId))
+		}
+		info.PublicIPs = redshiftPublicIPs(cluster)
+		infos = append(infos, info)
+	}
+
+	if len(infos) > 0 {
+		// Without their ENIs, the RDS DB instances are listed without their
+		// public IPs, while the Redshift clusters list theirs
+		enis, err := c.fetchDatabaseNetworkInterfaces(ctx, region)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		unmatched := mapDatabaseNetworkInterfaces(infos, rdsSubnets, enis)
+		for _, service := range []string{ServiceRDS, ServiceRedshift} {
+			if listed[service] && len(unmatched[service]) > 0 {
+				errs = append(errs, fmt.Errorf("couldn't tell the %s databases of the public ENIs %s, whose IPs are left out of the databases",
+					service, strings.Join(unmatched[service], ", ")))
+			}
+		}
+
+		// Without the EIPs, the BYOIP addresses are charged like the others
+		owners, err := c.fetchIPOwners(ctx, region)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for i := range infos {
+			info := &infos[i]
+			info.IPCount = len(info.PublicIPs)
+			info.UnchargedIPCount = owners.uncharged(info.PublicIPs)
+			info.Cost = c.Pricing.MonthlyCost(region, info.IPCount-info.UnchargedIPCount)
+		}
+
+		if err := c.flagPrivateCandidates(ctx, region, infos); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, domain := range domains {
+		infos = append(infos, DatabaseInfo{
+			Profile:  c.Profile,
+			Account:  c.Account,
+			Region:   region,
+			Service:  ServiceOpenSearch,
+			ID:       aws.ToString(domain.DomainName),
+			Engine:   engineTypes[aws.ToString(domain.DomainName)],
+			Status:   openSearchDomainStatus(domain),
+			Endpoint: aws.ToString(domain.Endpoint),
+		})
+	}
+	return infos, errors.Join(errs...)
+}
+
+// fetchPublicRDSInstances returns the publicly accessible RDS DB instances of
+// a region, including those of Aurora and Multi-AZ DB clusters. The Multi-AZ
+// DB clusters are made publicly accessible as a whole, so their instances are
+// listed along with those of the publicly accessible clusters. Without the
+// clusters, only the instances publicly accessible themselves are.
+func (c *Collector) fetchPublicRDSInstances(ctx context.Context, region string) ([]rdstypes.DBInstance, error) {
+	publicClusters, clustersErr := c.fetchPublicRDSClusters(ctx, region)
+
+	var instances []rdstypes.DBInstance
+	paginator := rds.NewDescribeDBInstancesPaginator(c.clients.RDS(region), &rds.DescribeDBInstancesInput{
+		MaxRecords: aws.Int32(databasePageSize),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe RDS DB instances: %w", err)
+		}
+		c.recordScan(region, "DescribeDBInstances", 1, len(resp.DBInstances))
+
+		for _, instance := range resp.DBInstances {
+			if instance.PubliclyAccessible || publicClusters[aws.ToString(instance.DBClusterIdentifier)] {
+				instances = append(instances, instance)
+			}
+		}
+	}
+	return instances, clustersErr
+}
+
+// fetchPublicRDSClusters returns the IDs of the publicly accessible RDS DB
+// clusters of a region.
+func (c *Collector) fetchPublicRDSClusters(ctx context.Context, region string) (map[string]bool, error) {
+	clusters := map[string]bool{}
+	paginator := rds.NewDescribeDBClustersPaginator(c.clients.RDS(region), &rds.DescribeDBClustersInput{
+		MaxRecords: aws.Int32(databasePageSize),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe RDS DB clusters: %w", err)
+		}
+		c.recordScan(region, "DescribeDBClusters", 1, len(resp.DBClusters))
+
+		for _, cluster := range resp.DBClusters {
+			if aws.ToBool(cluster.PubliclyAccessible) {
+				clusters[aws.ToString(cluster.DBClusterIdentifier)] = true
+			}
+		}
+	}
+	return clusters, nil
+}
+
+// fetchPublicRedshiftClusters returns the publicly accessible Redshift
+// clusters of a region.
+func (c *Collector) fetchPublicRedshiftClusters(ctx context.Context, region string) ([]redshifttypes.Cluster, error) {
+	var clusters []redshifttypes.Cluster
+	paginator := redshift.NewDescribeClustersPaginator(c.clients.Redshift(region), &redshift.DescribeClustersInput{
+		MaxRecords: aws.Int32(databasePageSize),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe Redshift clusters: %w", err)
+		}
+		c.recordScan(region, "DescribeClusters", 1, len(resp.Clusters))
+
+		for _, cluster := range resp.Clusters {
+			if cluster.PubliclyAccessible {
+				clusters = append(clusters, cluster)
+			}
+		}
+	}
+	return clusters, nil
+}
+
+// fetchPublicOpenSearchDomains returns the OpenSearch and Elasticsearch
+// domains of a region with a public endpoint, which are those outside of VPCs,
+// along with the engine type of each domain name.
+func (c *Collector) fetchPublicOpenSearchDomains(ctx context.Context, region string) ([]opensearchtypes.DomainStatus, map[string]string, error) {
+	client := c.clients.OpenSearch(region)
+
+	resp, err := client.ListDomainNames(ctx, &opensearch.ListDomainNamesInput{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list OpenSearch domains: %w", err)
+	}
+	c.recordScan(region, "ListDomainNames", 1, len(resp.DomainNames))
+
+	var names []string
+	engineTypes := map[string]string{}
+	for _, domain := range resp.DomainNames {
+		names = append(names, aws.ToString(domain.DomainName))
+		engineTypes[aws.ToString(domain.DomainName)] = string(domain.EngineType)
+	}
+
+	var domains []opensearchtypes.DomainStatus
+	for start := 0; start < len(names); start += openSearchDescribedDomains {
+		end := min(start+openSearchDescribedDomains, len(names))
+		resp, err := client.DescribeDomains(ctx, &opensearch.DescribeDomainsInput{DomainNames: names[start:end]})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to describe OpenSearch domains: %w", err)
+		}
+		c.recordScan(region, "DescribeDomains", 1, len(resp.DomainStatusList))
+
+		for _, domain := range resp.DomainStatusList {
+			if domain.VPCOptions == nil {
+				domains = append(domains, domain)
+			}
+		}
+	}
+	return domains, engineTypes, nil
+}
+
+// openSearchDomainStatus tells the status of an OpenSearch domain from its
+// flags, as the API doesn't have any status field. The deleted domains are
+// still being cleaned up.
+func openSearchDomainStatus(domain opensearchtypes.DomainStatus) string {
+	switch {
+	case aws.ToBool(domain.Deleted):
+		return "deleting"
+	case domain.Created != nil && !aws.ToBool(domain.Created):
+		return "creating"
+	case aws.ToBool(domain.Processing):
+		return "processing"
+	}
+	return "active"
+}
+
+// redshiftPublicIPs returns the public IPs of the nodes of a Redshift cluster
+// and its Elastic IP, which is the public IP of its leader node.
+func redshiftPublicIPs(cluster redshifttypes.Cluster) []string {
+	var ips []string
+	for _, node := range cluster.ClusterNodes {
+		if ip := aws.ToString(node.PublicIPAddress); ip != "" && !slices.Contains(ips, ip) {
+			ips = append(ips, ip)
+		}
+	}
+	if cluster.ElasticIpStatus != nil {
+		if ip := aws.ToString(cluster.ElasticIpStatus.ElasticIp); ip != "" && !slices.Contains(ips, ip) {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// fetchDatabaseNetworkInterfaces returns the ENIs created by RDS and Redshift
+// in a region.
+func (c *Collector) fetchDatabaseNetworkInterfaces(ctx context.Context, region string) ([]types.NetworkInterface, error) {
+	var enis []types.NetworkInterface
+	pages, items := 0, 0
+
+	paginator := ec2.NewDescribeNetworkInterfacesPaginator(c.clients.EC2(region), &ec2.DescribeNetworkInterfacesInput{
+		Filters: []types.Filter{
+			{Name: aws.String("requester-id"), Values: []string{"amazon-rds", "amazon-redshift"}},
+		},
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe the database ENIs: %w", err)
+		}
+		pages++
+		items += len(resp.NetworkInterfaces)
+		enis = append(enis, resp.NetworkInterfaces...)
+	}
+
+	c.recordScan(region, "DescribeNetworkInterfaces (databases)", pages, items)
+	return enis, nil
+}
+
+// mapDatabaseNetworkInterfaces sets the ENIs of the RDS DB instances and
+// Redshift clusters, along with the public IPs of the RDS ones, and returns
+// the public ENIs of each service that couldn't be matched to any of them.
+//
+// The ENIs are told apart by their requester and description. The RDS DB
+// instances don't list their ENIs, which are those in the subnets of their
+// DB subnet group with exactly their security groups, and are left unmatched
+// when several instances share both. The Redshift clusters list the public
+// IPs of their nodes, which are matched to their ENIs.
+func mapDatabaseNetworkInterfaces(infos []DatabaseInfo, rdsSubnets map[int][]string, enis []types.NetworkInterface) map[string][]string {
+	unmatched := map[string][]string{}
+	for _, eni := range enis {
+		ips := eniPublicIPs(eni)
+		if len(ips) == 0 {
+			continue
+		}
+		eniID := aws.ToString(eni.NetworkInterfaceId)
+
+		switch service, _ := classifyENI(eni); service {
+		case ServiceRDS:
+			var matches []int
+			for i, info := range infos {
+				if info.Service == ServiceRDS && slices.Contains(rdsSubnets[i], aws.ToString(eni.SubnetId)) &&
+					sameSecurityGroups(info.SecurityGroupIDs, eni.Groups) {
+					matches = append(matches, i)
+				}
+			}
+			if len(matches) != 1 {
+				unmatched[ServiceRDS] = append(unmatched[ServiceRDS], eniID)
+				continue
+			}
+			info := &infos[matches[0]]
+			info.ENIIDs = append(info.ENIIDs, eniID)
+			for _, ip := range ips {
+				info.PublicIPs = append(info.PublicIPs, ip.publicIP)
+			}
+		case ServiceRedshift:
+			i := slices.IndexFunc(infos, func(info DatabaseInfo) bool {
+				return info.Service == ServiceRedshift && slices.ContainsFunc(ips, func(ip eniPublicIP) bool {
+					return slices.Contains(info.PublicIPs, ip.publicIP)
+				})
+			})
+			if i < 0 {
+				unmatched[ServiceRedshift] = append(unmatched[ServiceRedshift], eniID)
+				continue
+			}
+			infos[i].ENIIDs = append(infos[i].ENIIDs, eniID)
+		}
+	}
+	return unmatched
+}
+
+// sameSecurityGroups tells whether an ENI has exactly the given security
+// groups.
+func sameSecurityGroups(groupIDs []string, groups []types.GroupIdentifier) bool {
+	if len(groupIDs) != len(groups) {
+		return false
+	}
+	for _, group := range groups {
+		if !slices.Contains(groupIDs, aws.ToString(group.GroupId)) {
+			return false
+		}
+	}
+	return true
+}
+
+// flagPrivateCandidates lists the security groups of the databases that allow
+// inbound traffic from public IPs, and flags the databases with charged public
+// IPs but none of them. Making them private only takes modifying their public
+// accessibility, which gets rid of their public IPs.
+func (c *Collector) flagPrivateCandidates(ctx context.Context, region string, infos []DatabaseInfo) error {
+	var groupIDs []string
+	for _, info := range infos {
+		for _, id := range info.SecurityGroupIDs {
+			if !slices.Contains(groupIDs, id) {
+				groupIDs = append(groupIDs, id)
+			}
+		}
+	}
+	if len(groupIDs) == 0 {
+		return nil
+	}
+
+	groups, err := c.fetchSecurityGroups(ctx, region, groupIDs)
+	if err != nil {
+		return err
+	}
+	publicIngress := map[string]bool{}
+	for _, group := range groups {
+		publicIngress[aws.ToString(group.GroupId)] = allowsPublicIngress(group)
+	}
+
+	for i := range infos {
+		info := &infos[i]
+		for _, id := range info.SecurityGroupIDs {
+			if publicIngress[id] {
+				info.PublicIngressGroups = append(info.PublicIngressGroups, id)
+			}
+		}
+		// Making private a database without any charged IP saves nothing
+		if info.Cost > 0 && len(info.PublicIngressGroups) == 0 {
+			info.PrivateCandidate = true
+			info.PrivateNote = PrivateNote(c.Pricing, info.Cost)
+		}
+	}
+	return nil
+}
+
+// PrivateNote tells the monthly savings of making private a database whose
+// public IPs cost the given amount.
+func PrivateNote(model *pricing.Model, cost float64) string {
+	return fmt.Sprintf("no security group allows access from the internet, making it private would save %s per month", model.Format(cost))
+}
+
+func (c *Collector) fetchSecurityGroups(ctx context.Context, region string, groupIDs []string) ([]types.SecurityGroup, error) {
+	var groups []types.SecurityGroup
+	pages := 0
+
+	paginator := ec2.NewDescribeSecurityGroupsPaginator(c.clients.EC2(region), &ec2.DescribeSecurityGroupsInput{
+		Filters: []types.Filter{{Name: aws.String("group-id"), Values: groupIDs}},
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe the database security groups: %w", err)
+		}
+		pages++
+		groups = append(groups, resp.SecurityGroups...)
+	}
+
+	c.recordScan(region, "DescribeSecurityGroups", pages, len(groups))
+	return groups, nil
+}
+
+// allowsPublicIngress tells whether a security group allows inbound IPv4 or
+// IPv6 traffic from outside of the private ranges. The prefix lists may
+// contain public ranges, so they're assumed to.
+func allowsPublicIngress(group types.SecurityGroup) bool {
+	for _, permission := range group.IpPermissions {
+		if len(permission.PrefixListIds) > 0 {
+			return true
+		}
+		var cidrs []string
+		for _, ipRange := range permission.IpRanges {
+			cidrs = append(cidrs, aws.ToString(ipRange.CidrIp))
+		}
+		for _, ipv6Range := range permission.Ipv6Ranges {
+			cidrs = append(cidrs, aws.ToString(ipv6Range.CidrIpv6))
+		}
+		for _, cidr := range cidrs {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				continue
+			}
+			if !slices.ContainsFunc(privatePrefixes, func(private netip.Prefix) bool {
+				return private.Bits() <= prefix.Bits() && private.Contains(prefix.Addr())
+			}) {
+				return true
+			}
+		}
+	}
+	return false
+}
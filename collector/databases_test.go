@@ -0,0 +1,236 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	opensearchtypes "github.com/aws/aws-sdk-go-v2/service/opensearch/types"
+	rdstypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	redshifttypes "github.com/aws/aws-sdk-go-v2/service/redshift/types"
+)
+
+func TestFetchAllDatabases(t *testing.T) {
+	dbInstance := func(id string, public bool) rdstypes.DBInstance {
+		return rdstypes.DBInstance{
+			DBInstanceIdentifier: aws.String(id),
+			DBInstanceStatus:     aws.String("available"),
+			Engine:               aws.String("postgres"),
+			PubliclyAccessible:   public,
+			Endpoint:             &rdstypes.Endpoint{Address: aws.String(id + ".rds.example.com"), Port: 5432},
+			DBSubnetGroup: &rdstypes.DBSubnetGroup{VpcId: aws.String("vpc-1"), Subnets: []rdstypes.Subnet{
+				{SubnetIdentifier: aws.String("subnet-a")}, {SubnetIdentifier: aws.String("subnet-b")},
+			}},
+			VpcSecurityGroups: []rdstypes.VpcSecurityGroupMembership{{VpcSecurityGroupId: aws.String("sg-db")}},
+		}
+	}
+	reporting := dbInstance("reporting", true)
+	reporting.DBClusterIdentifier = aws.String("analytics")
+	reporting.VpcSecurityGroups = []rdstypes.VpcSecurityGroupMembership{{VpcSecurityGroupId: aws.String("sg-reporting")}}
+	// The instances of a Multi-AZ DB cluster are public with their cluster
+	orders := dbInstance("orders", false)
+	orders.DBClusterIdentifier = aws.String("orders")
+	orders.VpcSecurityGroups = []rdstypes.VpcSecurityGroupMembership{{VpcSecurityGroupId: aws.String("sg-orders")}}
+
+	databaseENI := func(id, requester, description, subnet, group, publicIP string) types.NetworkInterface {
+		return types.NetworkInterface{
+			NetworkInterfaceId: aws.String(id),
+			RequesterManaged:   aws.Bool(true),
+			RequesterId:        aws.String(requester),
+			Description:        aws.String(description),
+			SubnetId:           aws.String(subnet),
+			Groups:             []types.GroupIdentifier{{GroupId: aws.String(group)}},
+			Association:        &types.NetworkInterfaceAssociation{PublicIp: aws.String(publicIP)},
+		}
+	}
+
+	c := New(&fakeClients{
+		ec2: map[string]*fakeEC2{"us-east-1": {
+			pageSize: 1,
+			networkInterfaces: []types.NetworkInterface{
+				databaseENI("eni-app", "amazon-rds", "RDSNetworkInterface", "subnet-a", "sg-db", "1.1.1.1"),
+				databaseENI("eni-reporting", "amazon-rds", "RDSNetworkInterface", "subnet-b", "sg-reporting", "1.1.1.2"),
+				databaseENI("eni-orders", "amazon-rds", "RDSNetworkInterface", "subnet-a", "sg-orders", "1.1.1.3"),
+				databaseENI("eni-warehouse", "amazon-redshift", "RedshiftNetworkInterface", "subnet-a", "sg-open", "2.2.2.1"),
+			},
+			securityGroups: []types.SecurityGroup{
+				{GroupId: aws.String("sg-db"), IpPermissions: []types.IpPermission{
+					{IpRanges: []types.IpRange{{CidrIp: aws.String("10.0.0.0/16")}}},
+					{UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: aws.String("sg-app")}}},
+				}},
+				{GroupId: aws.String("sg-open"), IpPermissions: []types.IpPermission{
+					{IpRanges: []types.IpRange{{CidrIp: aws.String("0.0.0.0/0")}}},
+				}},
+			},
+		}},
+		rds: map[string]*fakeRDS{"us-east-1": {
+			pageSize:    1,
+			dbInstances: []rdstypes.DBInstance{dbInstance("app", true), reporting, orders, dbInstance("internal", false)},
+			dbClusters: []rdstypes.DBCluster{
+				{DBClusterIdentifier: aws.String("analytics"), PubliclyAccessible: aws.Bool(false)},
+				{DBClusterIdentifier: aws.String("orders"), PubliclyAccessible: aws.Bool(true)},
+			},
+		}},
+		redshift: map[string]*fakeRedshift{"us-east-1": {clusters: []redshifttypes.Cluster{
+			{
+				ClusterIdentifier:  aws.String("warehouse"),
+				ClusterStatus:      aws.String("available"),
+				PubliclyAccessible: true,
+				Endpoint:           &redshifttypes.Endpoint{Address: aws.String("warehouse.redshift.example.com")},
+				VpcId:              aws.String("vpc-1"),
+				ClusterNodes:       []redshifttypes.ClusterNode{{NodeRole: aws.String("SHARED"), PublicIPAddress: aws.String("2.2.2.1")}},
+				VpcSecurityGroups:  []redshifttypes.VpcSecurityGroupMembership{{VpcSecurityGroupId: aws.String("sg-open")}},
+			},
+			{ClusterIdentifier: aws.String("private")},
+		}}},
+		opensearch: map[string]*fakeOpenSearch{"us-east-1": {
+			domains: []opensearchtypes.DomainStatus{
+				{DomainName: aws.String("logs"), EngineVersion: aws.String("OpenSearch_2.11"), Endpoint: aws.String("search-logs.es.example.com"),
+					Created: aws.Bool(true)},
+				{DomainName: aws.String("in-vpc"), VPCOptions: &opensearchtypes.VPCDerivedInfo{VPCId: aws.String("vpc-1")}},
+				{DomainName: aws.String("legacy"), EngineVersion: aws.String("Elasticsearch_7.10"), Created: aws.Bool(true),
+					Processing: aws.Bool(true)},
+				{DomainName: aws.String("gone"), EngineVersion: aws.String("OpenSearch_2.11"), Created: aws.Bool(true), Deleted: aws.Bool(true)},
+			},
+			engineTypes: map[string]opensearchtypes.EngineType{"logs": "OpenSearch", "in-vpc": "OpenSearch", "legacy": "Elasticsearch", "gone": "OpenSearch"},
+		}},
+	})
+
+	databases, err := c.FetchAllDatabases(context.Background(), []string{"us-east-1"})
+	if err != nil {
+		t.Fatalf("FetchAllDatabases() error = %v", err)
+	}
+
+	note := "no security group allows access from the internet, making it private would save " + c.Pricing.Format(3.65) + " per month"
+	want := []DatabaseInfo{
+		{Region: "us-east-1", Service: ServiceRDS, ID: "app", Engine: "postgres", Status: "available", Endpoint: "app.rds.example.com",
+			VPCID: "vpc-1", SecurityGroupIDs: []string{"sg-db"}, ENIIDs: []string{"eni-app"}, IPCount: 1, PublicIPs: []string{"1.1.1.1"},
+			Cost: 3.65, PrivateCandidate: true, PrivateNote: note},
+		{Region: "us-east-1", Service: ServiceRDS, ID: "reporting", Cluster: "analytics", Engine: "postgres", Status: "available",
+			Endpoint: "reporting.rds.example.com", VPCID: "vpc-1", SecurityGroupIDs: []string{"sg-reporting"}, ENIIDs: []string{"eni-reporting"},
+			IPCount: 1, PublicIPs: []string{"1.1.1.2"}, Cost: 3.65, PrivateCandidate: true, PrivateNote: note},
+		{Region: "us-east-1", Service: ServiceRDS, ID: "orders", Cluster: "orders", Engine: "postgres", Status: "available",
+			Endpoint: "orders.rds.example.com", VPCID: "vpc-1", SecurityGroupIDs: []string{"sg-orders"}, ENIIDs: []string{"eni-orders"},
+			IPCount: 1, PublicIPs: []string{"1.1.1.3"}, Cost: 3.65, PrivateCandidate: true, PrivateNote: note},
+		{Region: "us-east-1", Service: ServiceRedshift, ID: "warehouse", Engine: EngineRedshift, Status: "available",
+			Endpoint: "warehouse.redshift.example.com", VPCID: "vpc-1", SecurityGroupIDs: []string{"sg-open"}, ENIIDs: []string{"eni-warehouse"},
+			IPCount: 1, PublicIPs: []string{"2.2.2.1"}, Cost: 3.65, PublicIngressGroups: []string{"sg-open"}},
+		{Region: "us-east-1", Service: ServiceOpenSearch, ID: "logs", Engine: "OpenSearch", Status: "active",
+			Endpoint: "search-logs.es.example.com"},
+		{Region: "us-east-1", Service: ServiceOpenSearch, ID: "legacy", Engine: "Elasticsearch", Status: "processing"},
+		{Region: "us-east-1", Service: ServiceOpenSearch, ID: "gone", Engine: "OpenSearch", Status: "deleting"},
+	}
+	if !reflect.DeepEqual(databases, want) {
+		t.Errorf("FetchAllDatabases() = %+v, want %+v", databases, want)
+	}
+}
+
+func TestUnmatchedDatabaseENIs(t *testing.T) {
+	// The two RDS DB instances share their subnets and security groups, so
+	// their ENI can't be told apart
+	dbInstance := func(id string) rdstypes.DBInstance {
+		return rdstypes.DBInstance{
+			DBInstanceIdentifier: aws.String(id),
+			PubliclyAccessible:   true,
+			DBSubnetGroup:        &rdstypes.DBSubnetGroup{Subnets: []rdstypes.Subnet{{SubnetIdentifier: aws.String("subnet-a")}}},
+			VpcSecurityGroups:    []rdstypes.VpcSecurityGroupMembership{{VpcSecurityGroupId: aws.String("sg-db")}},
+		}
+	}
+	c := New(&fakeClients{
+		ec2: map[string]*fakeEC2{"us-east-1": {networkInterfaces: []types.NetworkInterface{{
+			NetworkInterfaceId: aws.String("eni-db"),
+			RequesterManaged:   aws.Bool(true),
+			RequesterId:        aws.String("amazon-rds"),
+			Description:        aws.String("RDSNetworkInterface"),
+			SubnetId:           aws.String("subnet-a"),
+			Groups:             []types.GroupIdentifier{{GroupId: aws.String("sg-db")}},
+			Association:        &types.NetworkInterfaceAssociation{PublicIp: aws.String("1.1.1.1")},
+		}}}},
+		rds: map[string]*fakeRDS{"us-east-1": {dbInstances: []rdstypes.DBInstance{dbInstance("app"), dbInstance("reporting")}}},
+	})
+
+	databases, err := c.FetchAllDatabases(context.Background(), []string{"us-east-1"})
+
+	var regionErr *RegionError
+	if !errors.As(err, &regionErr) || !strings.Contains(err.Error(), "eni-db") {
+		t.Errorf("FetchAllDatabases() error = %v, want a *RegionError naming eni-db", err)
+	}
+	if len(databases) != 2 || databases[0].IPCount != 0 || databases[1].IPCount != 0 {
+		t.Errorf("FetchAllDatabases() = %+v, want both DB instances without IPs", databases)
+	}
+}
+
+func TestAllowsPublicIngress(t *testing.T) {
+	tests := []struct {
+		name       string
+		permission types.IpPermission
+		want       bool
+	}{
+		{"anywhere", types.IpPermission{IpRanges: []types.IpRange{{CidrIp: aws.String("0.0.0.0/0")}}}, true},
+		{"public address", types.IpPermission{IpRanges: []types.IpRange{{CidrIp: aws.String("203.0.113.7/32")}}}, true},
+		{"wider than a private range", types.IpPermission{IpRanges: []types.IpRange{{CidrIp: aws.String("172.0.0.0/8")}}}, true},
+		{"IPv6 anywhere", types.IpPermission{Ipv6Ranges: []types.Ipv6Range{{CidrIpv6: aws.String("::/0")}}}, true},
+		{"IPv6 public range", types.IpPermission{Ipv6Ranges: []types.Ipv6Range{{CidrIpv6: aws.String("2600:1f18::/36")}}}, true},
+		{"IPv6 unique local range", types.IpPermission{Ipv6Ranges: []types.Ipv6Range{{CidrIpv6: aws.String("fd00:ec2::/32")}}}, false},
+		{"prefix list", types.IpPermission{PrefixListIds: []types.PrefixListId{{PrefixListId: aws.String("pl-1")}}}, true},
+		{"private range", types.IpPermission{IpRanges: []types.IpRange{{CidrIp: aws.String("172.31.0.0/16")}}}, false},
+		{"shared range", types.IpPermission{IpRanges: []types.IpRange{{CidrIp: aws.String("100.64.1.0/24")}}}, false},
+		{"security group", types.IpPermission{UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: aws.String("sg-1")}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := types.SecurityGroup{IpPermissions: []types.IpPermission{tt.permission}}
+			if got := allowsPublicIngress(group); got != tt.want {
+				t.Errorf("allowsPublicIngress() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchAllDatabasesError(t *testing.T) {
+	c := New(&fakeClients{
+		rds: map[string]*fakeRDS{"us-east-1": {err: errors.New("AccessDenied")}},
+		redshift: map[string]*fakeRedshift{"us-east-1": {clusters: []redshifttypes.Cluster{{
+			ClusterIdentifier:  aws.String("warehouse"),
+			PubliclyAccessible: true,
+			ClusterNodes:       []redshifttypes.ClusterNode{{PublicIPAddress: aws.String("2.2.2.1")}},
+		}}}},
+		opensearch: map[string]*fakeOpenSearch{"eu-west-1": {domains: []opensearchtypes.DomainStatus{{DomainName: aws.String("logs")}}}},
+	})
+
+	databases, err := c.FetchAllDatabases(context.Background(), []string{"us-east-1", "eu-west-1"})
+
+	var regionErr *RegionError
+	if !errors.As(err, &regionErr) || regionErr.Region != "us-east-1" {
+		t.Errorf("FetchAllDatabases() error = %v, want a *RegionError for us-east-1", err)
+	}
+	// The other services of the region and the other regions are still scanned
+	ids := map[string]string{}
+	for _, database := range databases {
+		ids[database.ID] = database.Region
+	}
+	if len(databases) != 2 || ids["warehouse"] != "us-east-1" || ids["logs"] != "eu-west-1" {
+		t.Errorf("FetchAllDatabases() = %+v, want the Redshift cluster of us-east-1 and the OpenSearch domain of eu-west-1", databases)
+	}
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// EC2InstanceInfo is an EC2 instance with public IPs. PublicIP is the primary
+// one, while PublicIPs lists those of all its ENIs and private IPs.
+type EC2InstanceInfo struct {
+	Profile       string   `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Account       string   `json:"account" yaml:"account"`
+	Region        string   `json:"region" yaml:"region"`
+	NameTag       string   `json:"name_tag" yaml:"name_tag"`
+	InstanceState string   `json:"instance_state" yaml:"instance_state"`
+	InstanceID    string   `json:"instance_id" yaml:"instance_id"`
+	PublicIP      string   `json:"public_ip" yaml:"public_ip"`
+	PublicIPs     []string `json:"public_ips" yaml:"public_ips"`
+	IPCount       int      `json:"ip_count" yaml:"ip_count"`
+	VPCID         string   `json:"vpc_id" yaml:"vpc_id"`
+	SubnetID      string   `json:"subnet_id" yaml:"subnet_id"`
+	// UnchargedIPCount are the public IPs that aren't owned by Amazon, such
+	// as BYOIP EIPs, which are left out of the costs
+	UnchargedIPCount int     `json:"uncharged_ip_count" yaml:"uncharged_ip_count"`
+	Cost             float64 `json:"cost" yaml:"cost"`
+}
+
+// instancePublicIPs returns the public IPs associated with all the private IPs
+// of all the ENIs of an instance, starting with the primary one.
+func instancePublicIPs(instance types.Instance) []string {
+	var ips []string
+	seen := map[string]bool{}
+	add := func(ip string) {
+		if ip != "" && !seen[ip] {
+			seen[ip] = true
+			ips = append(ips, ip)
+		}
+	}
+
+	add(aws.ToString(instance.PublicIpAddress))
+	for _, eni := range instance.NetworkInterfaces {
+		for _, private := range eni.PrivateIpAddresses {
+			if private.Association != nil {
+				add(aws.ToString(private.Association.PublicIp))
+			}
+		}
+		if eni.Association != nil {
+			add(aws.ToString(eni.Association.PublicIp))
+		}
+	}
+	return ips
+}
+
+func (c *Collector) fetchInstancesInRegion(ctx context.Context, regionName string) ([]types.Instance, error) {
+	var filteredInstances []types.Instance
+	pages, items := 0, 0
+
+	// Fetch instances in the region
+	paginator := ec2.NewDescribeInstancesPaginator(c.clients.EC2(regionName), &ec2.DescribeInstancesInput{})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe instances: %w", err)
+		}
+		pages++
+
+		for _, reservation := range resp.Reservations {
+			for _, instance := range reservation.Instances {
+				items++
+				if len(instancePublicIPs(instance)) > 0 {
+					filteredInstances = append(filteredInstances, instance)
+				}
+			}
+		}
+	}
+
+	c.recordScan(regionName, "DescribeInstances", pages, items)
+	return filteredInstances, nil
+}
+
+// FetchAllInstances returns the EC2 instances that have a public IP from all
+// the given regions. When the EIPs of a region can't be described, its BYOIP
+// addresses are charged like the others, along with the error.
+func (c *Collector) FetchAllInstances(ctx context.Context, regions []string) ([]EC2InstanceInfo, error) {
+	var allInstances []EC2InstanceInfo
+	var errs []error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	debug.Println("Starting FetchAllInstances...")
+
+	for _, region := range regions {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+
+			debug.Printf("Fetching instances for region: %s", region)
+
+			instances, err := c.fetchInstancesInRegion(ctx, region)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, &RegionError{Account: c.Account, Region: region, Err: err})
+				mu.Unlock()
+				return
+			}
+
+			debug.Printf("Fetched %d instances for region %s", len(instances), region)
+
+			owners, err := c.fetchIPOwners(ctx, region)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, &RegionError{Account: c.Account, Region: region, Err: err})
+				mu.Unlock()
+			}
+
+			for _, instance := range instances {
+				nameTag := getNameTagValue(instance.Tags)
+				publicIPs := instancePublicIPs(instance)
+				inst := EC2InstanceInfo{
+					Profile:          c.Profile,
+					Account:          c.Account,
+					Region:           region,
+					NameTag:          nameTag,
+					InstanceState:    string(instance.State.Name),
+					InstanceID:       aws.ToString(instance.InstanceId),
+					PublicIP:         publicIPs[0],
+					PublicIPs:        publicIPs,
+					IPCount:          len(publicIPs),
+					VPCID:            aws.ToString(instance.VpcId),
+					SubnetID:         aws.ToString(instance.SubnetId),
+					UnchargedIPCount: owners.uncharged(publicIPs),
+				}
+				inst.Cost = c.Pricing.MonthlyCost(region, inst.IPCount-inst.UnchargedIPCount)
+				mu.Lock()
+				allInstances = append(allInstances, inst)
+				mu.Unlock()
+			}
+
+		}(region)
+	}
+
+	wg.Wait()
+
+	debug.Printf("Finished FetchAllInstances. Total instances fetched: %d", len(allInstances))
+
+	return allInstances, errors.Join(errs...)
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestFetchAllInstances(t *testing.T) {
+	c := New(&fakeClients{ec2: map[string]*fakeEC2{
+		"us-east-1": {reservations: []types.Reservation{{
+			Instances: []types.Instance{
+				{
+					InstanceId:      aws.String("i-public"),
+					PublicIpAddress: aws.String("1.2.3.4"),
+					State:           &types.InstanceState{Name: types.InstanceStateNameRunning},
+					VpcId:           aws.String("vpc-1"),
+					SubnetId:        aws.String("subnet-1"),
+					Tags:            []types.Tag{{Key: aws.String("aws:autoscaling:groupName"), Value: aws.String("my-asg")}},
+				},
+				{
+					InstanceId:      aws.String("i-multi"),
+					PublicIpAddress: aws.String("5.5.5.5"),
+					State:           &types.InstanceState{Name: types.InstanceStateNameRunning},
+					NetworkInterfaces: []types.InstanceNetworkInterface{
+						{PrivateIpAddresses: []types.InstancePrivateIpAddress{
+							{Association: &types.InstanceNetworkInterfaceAssociation{PublicIp: aws.String("5.5.5.5")}},
+							{Association: &types.InstanceNetworkInterfaceAssociation{PublicIp: aws.String("6.6.6.6")}},
+							{PrivateIpAddress: aws.String("10.0.0.3")},
+						}},
+						{PrivateIpAddresses: []types.InstancePrivateIpAddress{
+							{Association: &types.InstanceNetworkInterfaceAssociation{PublicIp: aws.String("7.7.7.7")}},
+						}},
+					},
+				},
+				{
+					InstanceId: aws.String("i-private"),
+					State:      &types.InstanceState{Name: types.InstanceStateNameRunning},
+				},
+				{
+					InstanceId:      aws.String("i-empty-ip"),
+					PublicIpAddress: aws.String(""),
+					State:           &types.InstanceState{Name: types.InstanceStateNameStopped},
+				},
+			},
+		}}},
+	}})
+
+	instances, err := c.FetchAllInstances(context.Background(), []string{"us-east-1", "eu-west-1"})
+	if err != nil {
+		t.Fatalf("FetchAllInstances() error = %v", err)
+	}
+
+	got := map[string]EC2InstanceInfo{}
+	for _, instance := range instances {
+		got[instance.InstanceID] = instance
+	}
+	want := map[string]EC2InstanceInfo{
+		"i-public": {
+			Region:        "us-east-1",
+			NameTag:       "my-asg",
+			InstanceState: "running",
+			InstanceID:    "i-public",
+			PublicIP:      "1.2.3.4",
+			PublicIPs:     []string{"1.2.3.4"},
+			IPCount:       1,
+			VPCID:         "vpc-1",
+			SubnetID:      "subnet-1",
+			Cost:          3.65,
+		},
+		"i-multi": {
+			Region:        "us-east-1",
+			InstanceState: "running",
+			InstanceID:    "i-multi",
+			PublicIP:      "5.5.5.5",
+			PublicIPs:     []string{"5.5.5.5", "6.6.6.6", "7.7.7.7"},
+			IPCount:       3,
+			Cost:          10.95,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FetchAllInstances() = %+v, want %+v", instances, want)
+	}
+}
@@ -0,0 +1,309 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/pricing"
+)
+
+// EIPInfo is an Elastic IP allocated in an account. InstanceID is set for the
+// EIPs attached to EC2 instances, which are also counted with the instances.
+// Associated is set for the EIPs associated with an ENI, even when its owner
+// isn't known, and only the others are charged the idle surcharge. The EIPs
+// that aren't owned by Amazon aren't charged at all.
+type EIPInfo struct {
+	Profile            string            `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Account            string            `json:"account" yaml:"account"`
+	Region             string            `json:"region" yaml:"region"`
+	PublicIP           string            `json:"public_ip" yaml:"public_ip"`
+	AllocationID       string            `json:"allocation_id" yaml:"allocation_id"`
+	AssociationID      string            `json:"association_id,omitempty" yaml:"association_id,omitempty"`
+	AssociationTarget  string            `json:"association_target" yaml:"association_target"`
+	Associated         bool              `json:"associated" yaml:"associated"`
+	InstanceID         string            `json:"instance_id,omitempty" yaml:"instance_id,omitempty"`
+	NetworkInterfaceID string            `json:"network_interface_id,omitempty" yaml:"network_interface_id,omitempty"`
+	PrivateIP          string            `json:"private_ip,omitempty" yaml:"private_ip,omitempty"`
+	Domain             string            `json:"domain" yaml:"domain"`
+	NetworkBorderGroup string            `json:"network_border_group" yaml:"network_border_group"`
+	PublicIPv4Pool     string            `json:"public_ipv4_pool" yaml:"public_ipv4_pool"`
+	IPOwner            string            `json:"ip_owner" yaml:"ip_owner"`
+	NameTag            string            `json:"name_tag" yaml:"name_tag"`
+	Tags               map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Cost               float64           `json:"cost" yaml:"cost"`
+}
+
+const (
+	FilterNameAssociationID         = "association-id"
+	FilterNameAllocationID          = "allocation-id"
+	FilterNameENIAllocationID       = "association.allocation-id"
+	AssociationTypeInstance         = "Instance"
+	AssociationTypeNATGateway       = "NAT Gateway"
+	AssociationTypeNetworkInterface = "Network Interface"
+)
+
+// fetchEIPsInRegion returns the EIPs of a region, shared by the EIP collector
+// and all those telling the BYOIP addresses apart.
+func (c *Collector) fetchEIPsInRegion(ctx context.Context, regionName string) ([]types.Address, error) {
+	return fetchShared(c, regionName, "DescribeAddresses", func() ([]types.Address, error) {
+		// DescribeAddresses isn't paginated, it always returns all the addresses
+		resp, err := c.clients.EC2(regionName).DescribeAddresses(ctx, &ec2.DescribeAddressesInput{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe EIPs: %w", err)
+		}
+
+		c.recordScan(regionName, "DescribeAddresses", 1, len(resp.Addresses))
+		return resp.Addresses, nil
+	})
+}
+
+// ipOwners are the owners of the public IPs of a region that aren't owned by
+// Amazon, by IP.
+type ipOwners map[string]string
+
+// owner returns the owner of a public IP, which is customer-owned when it's
+// associated with a customer-owned IP.
+func (o ipOwners) owner(publicIP string, customerOwnedIP *string) string {
+	if owner := ipOwner(nil, customerOwnedIP); !pricing.Charged(owner) {
+		return owner
+	}
+	if owner, ok := o[publicIP]; ok {
+		return owner
+	}
+	return pricing.IPOwnerAmazon
+}
+
+// uncharged counts the public IPs that aren't owned by Amazon, which are left
+// out of the costs.
+func (o ipOwners) uncharged(publicIPs []string) int {
+	uncharged := 0
+	for _, publicIP := range publicIPs {
+		if _, ok := o[publicIP]; ok {
+			uncharged++
+		}
+	}
+	return uncharged
+}
+
+// fetchIPOwners returns the owners of the public IPs of a region that aren't
+// owned by Amazon, from its EIPs, as only the EIPs tell the BYOIP addresses
+// apart. The collectors fetching it get the result of a single
+// DescribeAddresses call per region and scan.
+func (c *Collector) fetchIPOwners(ctx context.Context, regionName string) (ipOwners, error) {
+	eips, err := c.fetchEIPsInRegion(ctx, regionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to tell the BYOIP addresses apart: %w", err)
+	}
+
+	owners := ipOwners{}
+	for _, eip := range eips {
+		if owner := ipOwner(eip.PublicIpv4Pool, eip.CustomerOwnedIp); !pricing.Charged(owner) {
+			owners[aws.ToString(eip.PublicIp)] = owner
+		}
+	}
+	return owners, nil
+}
+
+// fetchNATGatewayAllocations returns the IDs of the NAT gateways of a region
+// by the allocation IDs of their EIPs.
+func (c *Collector) fetchNATGatewayAllocations(ctx context.Context, regionName string) (map[string]string, error) {
+	resp, err := c.fetchNATGatewaysInRegion(ctx, regionName)
+	if err != nil {
+		return nil, err
+	}
+
+	natGateways := map[string]string{}
+	for _, natGateway := range resp {
+		for _, address := range natGateway.NatGatewayAddresses {
+			if address.AllocationId != nil {
+				natGateways[*address.AllocationId] = aws.ToString(natGateway.NatGatewayId)
+			}
+		}
+	}
+	return natGateways, nil
+}
+
+// fetchEIPNetworkInterfaces returns the ENIs of a region that have an EIP
+// associated, by their ID.
+func (c *Collector) fetchEIPNetworkInterfaces(ctx context.Context, regionName string) (map[string]types.NetworkInterface, error) {
+	enis := map[string]types.NetworkInterface{}
+	pages, items := 0, 0
+
+	paginator := ec2.NewDescribeNetworkInterfacesPaginator(c.clients.EC2(regionName), &ec2.DescribeNetworkInterfacesInput{
+		Filters: []types.Filter{{Name: aws.String(FilterNameENIAllocationID), Values: []string{"eipalloc-*"}}},
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe the EIP ENIs: %w", err)
+		}
+		pages++
+		items += len(resp.NetworkInterfaces)
+
+		for _, eni := range resp.NetworkInterfaces {
+			enis[aws.ToString(eni.NetworkInterfaceId)] = eni
+		}
+	}
+
+	c.recordScan(regionName, "DescribeNetworkInterfaces (EIP)", pages, items)
+	return enis, nil
+}
+
+// eipAssociations resolves the resources the EIPs of a region are associated
+// with, other than EC2 instances, from indexes of the NAT gateways and ENIs.
+type eipAssociations struct {
+	natGateways map[string]string
+	enis        map[string]types.NetworkInterface
+}
+
+func (c *Collector) fetchEIPAssociations(ctx context.Context, regionName string, eips []types.Address) (*eipAssociations, error) {
+	associations := &eipAssociations{}
+	needed := false
+	for _, eip := range eips {
+		if eip.InstanceId == nil && eip.NetworkInterfaceId != nil {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		return associations, nil
+	}
+
+	var err error
+	if associations.natGateways, err = c.fetchNATGatewayAllocations(ctx, regionName); err != nil {
+		return nil, err
+	}
+	if associations.enis, err = c.fetchEIPNetworkInterfaces(ctx, regionName); err != nil {
+		return nil, err
+	}
+	return associations, nil
+}
+
+// target returns the resource an EIP is associated with, as "<type>: <ID>",
+// or an empty string for the unassociated EIPs. The EIPs on ENIs of no known
+// owner, including those the ENI scan didn't return, are associated with the
+// ENI itself.
+func (a *eipAssociations) target(eip types.Address) string {
+	switch {
+	case eip.InstanceId != nil:
+		return AssociationTypeInstance + ": " + aws.ToString(eip.InstanceId)
+	case eip.NetworkInterfaceId == nil:
+		return ""
+	}
+
+	if natGateway, ok := a.natGateways[aws.ToString(eip.AllocationId)]; ok {
+		return AssociationTypeNATGateway + ": " + natGateway
+	}
+	eniID := aws.ToString(eip.NetworkInterfaceId)
+	if eni, ok := a.enis[eniID]; ok {
+		if service, ownerID := classifyENI(eni); service != ServiceUnknown && service != ServiceUnattached {
+			return service + ": " + ownerID
+		}
+	}
+	return AssociationTypeNetworkInterface + ": " + eniID
+}
+
+// FetchAllEIPs returns all the Elastic IPs from all the given regions,
+// including those attached to EC2 instances. Unassociated EIPs are charged an
+// additional fee.
+// Each region takes a single DescribeAddresses call, plus one paginated scan
+// of the NAT gateways and one of the ENIs when some EIPs are associated with
+// ENIs.
+func (c *Collector) FetchAllEIPs(ctx context.Context, regions []string) ([]EIPInfo, error) {
+	var allEIPs []EIPInfo
+	var errs []error
+	var wg sync.WaitGroup
+
+	eipCh := make(chan EIPInfo, len(regions)*10)
+	errCh := make(chan error, len(regions))
+
+	for _, region := range regions {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+
+			eips, err := c.fetchEIPsInRegion(ctx, region)
+			if err != nil {
+				errCh <- &RegionError{Account: c.Account, Region: region, Err: err}
+				return
+			}
+
+			associations, err := c.fetchEIPAssociations(ctx, region, eips)
+			if err != nil {
+				errCh <- &RegionError{Account: c.Account, Region: region, Err: fmt.Errorf("failed to describe EIP associations: %w", err)}
+				return
+			}
+
+			for _, eip := range eips {
+				eipInfo := EIPInfo{
+					Profile:            c.Profile,
+					Account:            c.Account,
+					Region:             region,
+					PublicIP:           aws.ToString(eip.PublicIp),
+					AllocationID:       aws.ToString(eip.AllocationId),
+					AssociationID:      aws.ToString(eip.AssociationId),
+					AssociationTarget:  associations.target(eip),
+					Associated:         eip.AssociationId != nil || eip.NetworkInterfaceId != nil || eip.InstanceId != nil,
+					InstanceID:         aws.ToString(eip.InstanceId),
+					NetworkInterfaceID: aws.ToString(eip.NetworkInterfaceId),
+					PrivateIP:          aws.ToString(eip.PrivateIpAddress),
+					Domain:             string(eip.Domain),
+					NetworkBorderGroup: aws.ToString(eip.NetworkBorderGroup),
+					PublicIPv4Pool:     aws.ToString(eip.PublicIpv4Pool),
+					IPOwner:            ipOwner(eip.PublicIpv4Pool, eip.CustomerOwnedIp),
+					NameTag:            getNameTagValue(eip.Tags),
+					Tags:               getTags(eip.Tags),
+					Cost:               c.Pricing.MonthlyCost(region, 1),
+				}
+				switch {
+				case !pricing.Charged(eipInfo.IPOwner):
+					eipInfo.Cost = 0
+				case !eipInfo.Associated:
+					eipInfo.Cost += c.Pricing.IdleEIPMonthlySurcharge(region)
+				}
+
+				eipCh <- eipInfo
+			}
+		}(region)
+	}
+
+	go func() {
+		wg.Wait()
+		close(eipCh)
+		close(errCh)
+	}()
+
+	for eip := range eipCh {
+		allEIPs = append(allEIPs, eip)
+	}
+
+	for err := range errCh {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		debug.Printf("Encountered errors: %v", errs)
+	}
+	return allEIPs, errors.Join(errs...)
+}
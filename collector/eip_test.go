@@ -0,0 +1,358 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	redshifttypes "github.com/aws/aws-sdk-go-v2/service/redshift/types"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/pricing"
+)
+
+func TestFetchAllEIPs(t *testing.T) {
+	c := New(&fakeClients{ec2: map[string]*fakeEC2{
+		"us-east-1": {
+			addresses: []types.Address{
+				{
+					PublicIp:           aws.String("1.1.1.1"),
+					AllocationId:       aws.String("eipalloc-instance"),
+					AssociationId:      aws.String("eipassoc-instance"),
+					InstanceId:         aws.String("i-1"),
+					PrivateIpAddress:   aws.String("10.0.0.1"),
+					Domain:             types.DomainTypeVpc,
+					NetworkBorderGroup: aws.String("us-east-1"),
+					PublicIpv4Pool:     aws.String("ipv4pool-ec2-123"),
+				},
+				{
+					PublicIp:           aws.String("2.2.2.2"),
+					AllocationId:       aws.String("eipalloc-nat"),
+					AssociationId:      aws.String("eipassoc-nat"),
+					NetworkInterfaceId: aws.String("eni-nat"),
+					Tags:               []types.Tag{{Key: aws.String("Name"), Value: aws.String("nat-ip")}},
+				},
+				{
+					PublicIp:       aws.String("3.3.3.3"),
+					AllocationId:   aws.String("eipalloc-idle"),
+					PublicIpv4Pool: aws.String(PublicIPv4PoolAmazon),
+				},
+			},
+			natGateways: []types.NatGateway{{
+				NatGatewayId: aws.String("nat-1"),
+				NatGatewayAddresses: []types.NatGatewayAddress{
+					{AllocationId: aws.String("eipalloc-nat")},
+				},
+			}},
+		},
+	}})
+
+	eips, err := c.FetchAllEIPs(context.Background(), []string{"us-east-1"})
+	if err != nil {
+		t.Fatalf("FetchAllEIPs() error = %v", err)
+	}
+
+	got := map[string]EIPInfo{}
+	for _, eip := range eips {
+		got[eip.PublicIP] = eip
+	}
+
+	want := map[string]EIPInfo{
+		"1.1.1.1": {Region: "us-east-1", PublicIP: "1.1.1.1", AllocationID: "eipalloc-instance", AssociationID: "eipassoc-instance",
+			AssociationTarget: "Instance: i-1", Associated: true, InstanceID: "i-1", PrivateIP: "10.0.0.1", Domain: "vpc",
+			NetworkBorderGroup: "us-east-1", PublicIPv4Pool: "ipv4pool-ec2-123", IPOwner: "byoip"},
+		"2.2.2.2": {Region: "us-east-1", PublicIP: "2.2.2.2", AllocationID: "eipalloc-nat", AssociationID: "eipassoc-nat",
+			AssociationTarget: "NAT Gateway: nat-1", Associated: true, NetworkInterfaceID: "eni-nat", NameTag: "nat-ip",
+			IPOwner: "amazon", Tags: map[string]string{"Name": "nat-ip"}, Cost: 3.65},
+		"3.3.3.3": {Region: "us-east-1", PublicIP: "3.3.3.3", AllocationID: "eipalloc-idle", PublicIPv4Pool: "amazon", IPOwner: "amazon", Cost: 7.30},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("FetchAllEIPs() = %+v, want %+v", eips, want)
+	}
+	for ip, eip := range want {
+		if !reflect.DeepEqual(got[ip], eip) {
+			t.Errorf("EIP %s = %+v, want %+v", ip, got[ip], eip)
+		}
+	}
+}
+
+func TestFetchAllEIPsResolvesAssociationsInBulk(t *testing.T) {
+	ec2Client := &fakeEC2{
+		pageSize: 100,
+		addresses: []types.Address{
+			{
+				PublicIp:           aws.String("4.4.4.4"),
+				AllocationId:       aws.String("eipalloc-nlb"),
+				AssociationId:      aws.String("eipassoc-nlb"),
+				NetworkInterfaceId: aws.String("eni-nlb"),
+			},
+			{
+				PublicIp:           aws.String("5.5.5.5"),
+				AllocationId:       aws.String("eipalloc-other"),
+				AssociationId:      aws.String("eipassoc-other"),
+				NetworkInterfaceId: aws.String("eni-other"),
+			},
+		},
+		networkInterfaces: []types.NetworkInterface{{
+			NetworkInterfaceId: aws.String("eni-nlb"),
+			InterfaceType:      types.NetworkInterfaceTypeNetworkLoadBalancer,
+			Description:        aws.String("ELB net/my-nlb/123"),
+			RequesterManaged:   aws.Bool(true),
+		}},
+	}
+	for i := 0; i < 1000; i++ {
+		allocationID := fmt.Sprintf("eipalloc-nat-%d", i)
+		ec2Client.addresses = append(ec2Client.addresses, types.Address{
+			PublicIp:           aws.String(fmt.Sprintf("10.0.%d.%d", i/256, i%256)),
+			AllocationId:       aws.String(allocationID),
+			AssociationId:      aws.String(fmt.Sprintf("eipassoc-nat-%d", i)),
+			NetworkInterfaceId: aws.String(fmt.Sprintf("eni-nat-%d", i)),
+		})
+		ec2Client.natGateways = append(ec2Client.natGateways, types.NatGateway{
+			NatGatewayId:        aws.String(fmt.Sprintf("nat-%d", i)),
+			NatGatewayAddresses: []types.NatGatewayAddress{{AllocationId: aws.String(allocationID)}},
+		})
+	}
+	c := New(&fakeClients{ec2: map[string]*fakeEC2{"us-east-1": ec2Client}})
+
+	eips, err := c.FetchAllEIPs(context.Background(), []string{"us-east-1"})
+	if err != nil {
+		t.Fatalf("FetchAllEIPs() error = %v", err)
+	}
+
+	got := map[string]string{}
+	for _, eip := range eips {
+		got[eip.PublicIP] = eip.AssociationTarget
+	}
+	if len(got) != 1002 {
+		t.Fatalf("FetchAllEIPs() returned %d EIPs, want 1002", len(got))
+	}
+	for ip, target := range map[string]string{
+		"4.4.4.4":    "Network Load Balancer: net/my-nlb/123",
+		"5.5.5.5":    "Network Interface: eni-other",
+		"10.0.3.231": "NAT Gateway: nat-999",
+	} {
+		if got[ip] != target {
+			t.Errorf("EIP %s association = %q, want %q", ip, got[ip], target)
+		}
+	}
+
+	// 1 DescribeAddresses, and the 10 pages of the NAT gateways
+	want := map[string]int{"DescribeAddresses": 1, "DescribeNatGateways": 10, "DescribeNetworkInterfaces": 1}
+	for operation, calls := range want {
+		if ec2Client.calls[operation] != calls {
+			t.Errorf("%s calls = %d, want %d", operation, ec2Client.calls[operation], calls)
+		}
+	}
+}
+
+func TestEIPAssociationTarget(t *testing.T) {
+	associations := &eipAssociations{
+		natGateways: map[string]string{"eipalloc-nat": "nat-1"},
+		enis: map[string]types.NetworkInterface{
+			"eni-transfer": {
+				NetworkInterfaceId: aws.String("eni-transfer"),
+				Description:        aws.String("AWS Transfer Family endpoint"),
+				RequesterManaged:   aws.Bool(true),
+			},
+			"eni-ecs": {
+				NetworkInterfaceId: aws.String("eni-ecs"),
+				Description:        aws.String("arn:aws:ecs:us-east-1:123456789012:attachment/abc"),
+				RequesterManaged:   aws.Bool(true),
+			},
+			"eni-bare": {
+				NetworkInterfaceId: aws.String("eni-bare"),
+				Status:             types.NetworkInterfaceStatusAvailable,
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		eip  types.Address
+		want string
+	}{
+		{"instance", types.Address{InstanceId: aws.String("i-1"), NetworkInterfaceId: aws.String("eni-1")}, "Instance: i-1"},
+		{"NAT gateway", types.Address{AllocationId: aws.String("eipalloc-nat"), NetworkInterfaceId: aws.String("eni-nat")}, "NAT Gateway: nat-1"},
+		{"Transfer Family", types.Address{NetworkInterfaceId: aws.String("eni-transfer")}, "Transfer Family: eni-transfer"},
+		{"ECS task", types.Address{NetworkInterfaceId: aws.String("eni-ecs")}, "ECS Task: arn:aws:ecs:us-east-1:123456789012:attachment/abc"},
+		{"bare ENI", types.Address{NetworkInterfaceId: aws.String("eni-bare")}, "Network Interface: eni-bare"},
+		{"ENI not scanned", types.Address{NetworkInterfaceId: aws.String("eni-gone")}, "Network Interface: eni-gone"},
+		{"unassociated", types.Address{AllocationId: aws.String("eipalloc-idle")}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := associations.target(tt.eip); got != tt.want {
+				t.Errorf("target() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchAllEIPsError(t *testing.T) {
+	c := New(&fakeClients{ec2: map[string]*fakeEC2{
+		"us-east-1": {err: errors.New("throttled")},
+	}})
+
+	_, err := c.FetchAllEIPs(context.Background(), []string{"us-east-1"})
+
+	var regionErr *RegionError
+	if !errors.As(err, &regionErr) || regionErr.Region != "us-east-1" {
+		t.Errorf("FetchAllEIPs() error = %v, want a *RegionError for us-east-1", err)
+	}
+}
+
+func TestBYOIPNotChargedByCollectors(t *testing.T) {
+	const region = "us-east-1"
+	byoip := types.Address{PublicIp: aws.String("2.2.2.2"), AllocationId: aws.String("eipalloc-byoip"),
+		AssociationId: aws.String("eipassoc-byoip"), PublicIpv4Pool: aws.String("ipv4pool-ec2-123")}
+	ctx := context.Background()
+	rate := pricing.Default().Rate(region)
+
+	tests := []struct {
+		name     string
+		clients  func(ec2Client *fakeEC2) *fakeClients
+		fetch    func(c *Collector) (uncharged int, cost float64, err error)
+		wantCost float64
+	}{
+		{
+			name: "ENI",
+			clients: func(ec2Client *fakeEC2) *fakeClients {
+				ec2Client.networkInterfaces = []types.NetworkInterface{publicENI("eni-byoip", "2.2.2.2")}
+				return &fakeClients{ec2: map[string]*fakeEC2{region: ec2Client}}
+			},
+			fetch: func(c *Collector) (int, float64, error) {
+				enis, err := c.FetchAllENIs(ctx, []string{region})
+				if len(enis) != 1 || enis[0].IPOwner != pricing.IPOwnerBYOIP {
+					return 0, 0, fmt.Errorf("ENIs = %+v, want a BYOIP one", enis)
+				}
+				return 1, enis[0].Cost, err
+			},
+		},
+		{
+			name: "EC2 instance",
+			clients: func(ec2Client *fakeEC2) *fakeClients {
+				ec2Client.reservations = []types.Reservation{{Instances: []types.Instance{{
+					InstanceId:      aws.String("i-1"),
+					State:           &types.InstanceState{Name: types.InstanceStateNameRunning},
+					PublicIpAddress: aws.String("1.1.1.1"),
+					NetworkInterfaces: []types.InstanceNetworkInterface{{PrivateIpAddresses: []types.InstancePrivateIpAddress{
+						{Association: &types.InstanceNetworkInterfaceAssociation{PublicIp: aws.String("2.2.2.2")}},
+					}}},
+				}}}}
+				return &fakeClients{ec2: map[string]*fakeEC2{region: ec2Client}}
+			},
+			fetch: func(c *Collector) (int, float64, error) {
+				instances, err := c.FetchAllInstances(ctx, []string{region})
+				if len(instances) != 1 || instances[0].IPCount != 2 {
+					return 0, 0, fmt.Errorf("instances = %+v, want one with 2 IPs", instances)
+				}
+				return instances[0].UnchargedIPCount, instances[0].Cost, err
+			},
+			wantCost: 3.65,
+		},
+		{
+			name: "NLB",
+			clients: func(ec2Client *fakeEC2) *fakeClients {
+				ec2Client.networkInterfaces = []types.NetworkInterface{lbENI("ELB net/nlb/1", "1.1.1.1"), lbENI("ELB net/nlb/1", "2.2.2.2")}
+				return &fakeClients{
+					ec2: map[string]*fakeEC2{region: ec2Client},
+					elbv2: map[string]*fakeELBv2{region: {loadBalancers: []elbv2types.LoadBalancer{{
+						Type:            elbv2types.LoadBalancerTypeEnumNetwork,
+						Scheme:          elbv2types.LoadBalancerSchemeEnumInternetFacing,
+						LoadBalancerArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/nlb/1"),
+					}}}},
+					cloudwatch: map[string]*fakeCloudWatch{region: {values: map[string][]float64{"net/nlb/1 ProcessedBytes": {bytesInGB}}}},
+				}
+			},
+			fetch: func(c *Collector) (int, float64, error) {
+				lbs, err := c.FetchAllLoadBalancers(ctx, []string{region})
+				if len(lbs) != 1 {
+					return 0, 0, fmt.Errorf("load balancers = %+v, want one", lbs)
+				}
+				// The cost per GB only counts the charged IP
+				if want := rate * c.Lookback.Hours(); !almostEqual(lbs[0].CostPerGB, want) {
+					return 0, 0, fmt.Errorf("CostPerGB = %v, want %v", lbs[0].CostPerGB, want)
+				}
+				return lbs[0].UnchargedIPCount, lbs[0].Cost, err
+			},
+			wantCost: 3.65,
+		},
+		{
+			name: "VPN connection",
+			clients: func(ec2Client *fakeEC2) *fakeClients {
+				ec2Client.vpnConnections = []types.VpnConnection{{
+					VpnConnectionId: aws.String("vpn-1"),
+					State:           types.VpnStateAvailable,
+					Options: &types.VpnConnectionOptions{TunnelOptions: []types.TunnelOption{
+						{OutsideIpAddress: aws.String("1.1.1.1")}, {OutsideIpAddress: aws.String("2.2.2.2")},
+					}},
+				}}
+				return &fakeClients{ec2: map[string]*fakeEC2{region: ec2Client}}
+			},
+			fetch: func(c *Collector) (int, float64, error) {
+				vpnConnections, err := c.FetchAllVPNConnections(ctx, []string{region})
+				if len(vpnConnections) != 1 {
+					return 0, 0, fmt.Errorf("VPN connections = %+v, want one", vpnConnections)
+				}
+				return vpnConnections[0].UnchargedIPCount, vpnConnections[0].Cost, err
+			},
+			wantCost: 3.65,
+		},
+		{
+			// Making private a database without any charged IP saves nothing
+			name: "database",
+			clients: func(ec2Client *fakeEC2) *fakeClients {
+				ec2Client.securityGroups = []types.SecurityGroup{{GroupId: aws.String("sg-private")}}
+				return &fakeClients{
+					ec2: map[string]*fakeEC2{region: ec2Client},
+					redshift: map[string]*fakeRedshift{region: {clusters: []redshifttypes.Cluster{{
+						ClusterIdentifier:  aws.String("warehouse"),
+						PubliclyAccessible: true,
+						ClusterNodes:       []redshifttypes.ClusterNode{{PublicIPAddress: aws.String("2.2.2.2")}},
+						VpcSecurityGroups:  []redshifttypes.VpcSecurityGroupMembership{{VpcSecurityGroupId: aws.String("sg-private")}},
+					}}}},
+				}
+			},
+			fetch: func(c *Collector) (int, float64, error) {
+				databases, err := c.FetchAllDatabases(ctx, []string{region})
+				if len(databases) != 1 || databases[0].PrivateCandidate {
+					return 0, 0, fmt.Errorf("databases = %+v, want one that isn't a private candidate", databases)
+				}
+				return databases[0].UnchargedIPCount, databases[0].Cost, err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.clients(&fakeEC2{addresses: []types.Address{byoip}}))
+			uncharged, cost, err := tt.fetch(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if uncharged != 1 || !almostEqual(cost, tt.wantCost) {
+				t.Errorf("%d uncharged IPs costing %v, want 1 and %v", uncharged, cost, tt.wantCost)
+			}
+		})
+	}
+}
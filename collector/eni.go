@@ -0,0 +1,172 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/pricing"
+)
+
+// ENIInfo is a public IP of a network interface, attributed to the service and
+// resource owning the ENI. The ENIs with several public IPs, associated with
+// their secondary private IPs, have one ENIInfo for each of them.
+type ENIInfo struct {
+	Profile       string  `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Account       string  `json:"account" yaml:"account"`
+	Region        string  `json:"region" yaml:"region"`
+	PublicIP      string  `json:"public_ip" yaml:"public_ip"`
+	PrivateIP     string  `json:"private_ip" yaml:"private_ip"`
+	ENIID         string  `json:"eni_id" yaml:"eni_id"`
+	InterfaceType string  `json:"interface_type" yaml:"interface_type"`
+	Service       string  `json:"service" yaml:"service"`
+	OwnerID       string  `json:"owner_id" yaml:"owner_id"`
+	Description   string  `json:"description" yaml:"description"`
+	IPOwner       string  `json:"ip_owner" yaml:"ip_owner"`
+	Cost          float64 `json:"cost" yaml:"cost"`
+}
+
+// eniPublicIP is a public IP associated with one of the private IPs of an ENI
+type eniPublicIP struct {
+	publicIP        string
+	privateIP       string
+	customerOwnedIP *string
+}
+
+// eniPublicIPs returns the public IPs associated with all the private IPs of
+// an ENI, or with its primary private IP when they aren't listed.
+func eniPublicIPs(eni types.NetworkInterface) []eniPublicIP {
+	var ips []eniPublicIP
+	for _, private := range eni.PrivateIpAddresses {
+		if private.Association != nil && aws.ToString(private.Association.PublicIp) != "" {
+			ips = append(ips, eniPublicIP{
+				publicIP:        aws.ToString(private.Association.PublicIp),
+				privateIP:       aws.ToString(private.PrivateIpAddress),
+				customerOwnedIP: private.Association.CustomerOwnedIp,
+			})
+		}
+	}
+	if len(ips) == 0 && eni.Association != nil && aws.ToString(eni.Association.PublicIp) != "" {
+		ips = append(ips, eniPublicIP{
+			publicIP:        aws.ToString(eni.Association.PublicIp),
+			privateIP:       aws.ToString(eni.PrivateIpAddress),
+			customerOwnedIP: eni.Association.CustomerOwnedIp,
+		})
+	}
+	return ips
+}
+
+func (c *Collector) fetchENIsInRegion(ctx context.Context, regionName string) ([]types.NetworkInterface, error) {
+	var filteredENIs []types.NetworkInterface
+	pages, items := 0, 0
+
+	paginator := ec2.NewDescribeNetworkInterfacesPaginator(c.clients.EC2(regionName), &ec2.DescribeNetworkInterfacesInput{})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe ENIs: %w", err)
+		}
+		pages++
+		items += len(resp.NetworkInterfaces)
+
+		for _, eni := range resp.NetworkInterfaces {
+			if len(eniPublicIPs(eni)) > 0 {
+				filteredENIs = append(filteredENIs, eni)
+			}
+		}
+	}
+
+	c.recordScan(regionName, "DescribeNetworkInterfaces", pages, items)
+	return filteredENIs, nil
+}
+
+// FetchAllENIs returns the network interfaces that have a public IP from all
+// the given regions. When the EIPs of a region can't be described, its BYOIP
+// addresses are charged like the others, along with the error.
+func (c *Collector) FetchAllENIs(ctx context.Context, regions []string) ([]ENIInfo, error) {
+	var allENIs []ENIInfo
+	var errs []error
+	var wg sync.WaitGroup
+
+	eniCh := make(chan ENIInfo, len(regions)*10) // Assuming a max of 10 ENIs per region
+	errCh := make(chan error, len(regions))
+
+	for _, region := range regions {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+
+			enis, err := c.fetchENIsInRegion(ctx, region)
+			if err != nil {
+				errCh <- &RegionError{Account: c.Account, Region: region, Err: err}
+				return
+			}
+
+			owners, err := c.fetchIPOwners(ctx, region)
+			if err != nil {
+				errCh <- &RegionError{Account: c.Account, Region: region, Err: err}
+			}
+
+			for _, eni := range enis {
+				service, ownerID := classifyENI(eni)
+				for _, ip := range eniPublicIPs(eni) {
+					owner := owners.owner(ip.publicIP, ip.customerOwnedIP)
+					cost := c.Pricing.MonthlyCost(region, 1)
+					if !pricing.Charged(owner) {
+						cost = 0
+					}
+					eniCh <- ENIInfo{
+						Profile:       c.Profile,
+						Account:       c.Account,
+						Region:        region,
+						PublicIP:      ip.publicIP,
+						PrivateIP:     ip.privateIP,
+						ENIID:         aws.ToString(eni.NetworkInterfaceId),
+						InterfaceType: string(eni.InterfaceType),
+						Service:       service,
+						OwnerID:       ownerID,
+						Description:   aws.ToString(eni.Description),
+						IPOwner:       owner,
+						Cost:          cost,
+					}
+				}
+			}
+		}(region)
+	}
+
+	go func() {
+		wg.Wait()
+		close(eniCh)
+		close(errCh)
+	}()
+
+	for eni := range eniCh {
+		allENIs = append(allENIs, eni)
+	}
+
+	for err := range errCh {
+		errs = append(errs, err)
+	}
+
+	return allENIs, errors.Join(errs...)
+}
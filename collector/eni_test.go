@@ -0,0 +1,156 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func publicENI(id, publicIP string) types.NetworkInterface {
+	return types.NetworkInterface{
+		NetworkInterfaceId: aws.String(id),
+		Association:        &types.NetworkInterfaceAssociation{PublicIp: aws.String(publicIP)},
+	}
+}
+
+func TestFetchAllENIs(t *testing.T) {
+	c := New(&fakeClients{ec2: map[string]*fakeEC2{
+		"us-east-1": {networkInterfaces: []types.NetworkInterface{
+			publicENI("eni-public", "1.2.3.4"),
+			{NetworkInterfaceId: aws.String("eni-private")},
+			{NetworkInterfaceId: aws.String("eni-no-ip"), Association: &types.NetworkInterfaceAssociation{}},
+		}},
+		"eu-west-1": {networkInterfaces: []types.NetworkInterface{
+			publicENI("eni-other", "5.6.7.8"),
+			{
+				NetworkInterfaceId: aws.String("eni-multi"),
+				PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{
+					{PrivateIpAddress: aws.String("10.0.0.1"), Association: &types.NetworkInterfaceAssociation{PublicIp: aws.String("9.9.9.1")}},
+					{PrivateIpAddress: aws.String("10.0.0.2")},
+					{PrivateIpAddress: aws.String("10.0.0.3"), Association: &types.NetworkInterfaceAssociation{PublicIp: aws.String("9.9.9.3")}},
+				},
+			},
+		}},
+	}})
+
+	enis, err := c.FetchAllENIs(context.Background(), []string{"us-east-1", "eu-west-1"})
+	if err != nil {
+		t.Fatalf("FetchAllENIs() error = %v", err)
+	}
+
+	got := map[string]ENIInfo{}
+	for _, eni := range enis {
+		got[eni.PublicIP] = eni
+	}
+
+	want := map[string]ENIInfo{
+		"1.2.3.4": {Region: "us-east-1", PublicIP: "1.2.3.4", ENIID: "eni-public", Service: ServiceUnknown, OwnerID: "eni-public", IPOwner: "amazon", Cost: 3.65},
+		"5.6.7.8": {Region: "eu-west-1", PublicIP: "5.6.7.8", ENIID: "eni-other", Service: ServiceUnknown, OwnerID: "eni-other", IPOwner: "amazon", Cost: 3.65},
+		"9.9.9.1": {Region: "eu-west-1", PublicIP: "9.9.9.1", PrivateIP: "10.0.0.1", ENIID: "eni-multi", Service: ServiceUnknown, OwnerID: "eni-multi",
+			IPOwner: "amazon", Cost: 3.65},
+		"9.9.9.3": {Region: "eu-west-1", PublicIP: "9.9.9.3", PrivateIP: "10.0.0.3", ENIID: "eni-multi", Service: ServiceUnknown, OwnerID: "eni-multi",
+			IPOwner: "amazon", Cost: 3.65},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("FetchAllENIs() = %+v, want %+v", enis, want)
+	}
+	for ip, eni := range want {
+		if got[ip] != eni {
+			t.Errorf("ENI IP %s = %+v, want %+v", ip, got[ip], eni)
+		}
+	}
+}
+
+func TestFetchAllENIsRegionalPricing(t *testing.T) {
+	c := New(&fakeClients{ec2: map[string]*fakeEC2{
+		"eu-central-1": {networkInterfaces: []types.NetworkInterface{publicENI("eni-1", "1.2.3.4")}},
+	}})
+	c.Pricing.RegionHourlyRates = map[string]float64{"eu-central-1": 0.006}
+
+	enis, err := c.FetchAllENIs(context.Background(), []string{"eu-central-1"})
+	if err != nil {
+		t.Fatalf("FetchAllENIs() error = %v", err)
+	}
+	if len(enis) != 1 || !almostEqual(enis[0].Cost, 4.38) {
+		t.Errorf("FetchAllENIs() = %+v, want one ENI costing 4.38 with the regional rate", enis)
+	}
+}
+
+func TestClassifyENI(t *testing.T) {
+	managed := func(interfaceType types.NetworkInterfaceType, requester, description string) types.NetworkInterface {
+		return types.NetworkInterface{
+			NetworkInterfaceId: aws.String("eni-1"),
+			InterfaceType:      interfaceType,
+			RequesterId:        aws.String(requester),
+			RequesterManaged:   aws.Bool(true),
+			Description:        aws.String(description),
+			Status:             types.NetworkInterfaceStatusInUse,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		eni         types.NetworkInterface
+		wantService string
+		wantOwnerID string
+	}{
+		{"instance", types.NetworkInterface{
+			NetworkInterfaceId: aws.String("eni-1"),
+			InterfaceType:      types.NetworkInterfaceTypeInterface,
+			Attachment:         &types.NetworkInterfaceAttachment{InstanceId: aws.String("i-1")},
+		}, ServiceEC2Instance, "i-1"},
+		{"unattached", types.NetworkInterface{
+			NetworkInterfaceId: aws.String("eni-1"),
+			Status:             types.NetworkInterfaceStatusAvailable,
+		}, ServiceUnattached, "eni-1"},
+		{"ALB", managed(types.NetworkInterfaceTypeInterface, "amazon-elb", "ELB app/web/50dc6c495c0c9188"), ServiceALB, "app/web/50dc6c495c0c9188"},
+		{"NLB", managed(types.NetworkInterfaceTypeNetworkLoadBalancer, "amazon-elb", "ELB net/api/50dc6c495c0c9188"), ServiceNLB, "net/api/50dc6c495c0c9188"},
+		{"classic ELB", managed(types.NetworkInterfaceTypeInterface, "amazon-elb", "ELB legacy"), ServiceClassicELB, "legacy"},
+		{"NAT gateway", managed(types.NetworkInterfaceTypeNatGateway, "123456789012", "Interface for NAT Gateway nat-1"), ServiceNATGateway, "nat-1"},
+		{"Fargate task", managed(types.NetworkInterfaceTypeInterface, "578734482556",
+			"arn:aws:ecs:us-east-1:123456789012:attachment/e3a1d4c2"), ServiceECS, "arn:aws:ecs:us-east-1:123456789012:attachment/e3a1d4c2"},
+		{"Fargate task in China", managed(types.NetworkInterfaceTypeInterface, "578734482556",
+			"arn:aws-cn:ecs:cn-north-1:123456789012:attachment/e3a1d4c2"), ServiceECS, "arn:aws-cn:ecs:cn-north-1:123456789012:attachment/e3a1d4c2"},
+		{"Fargate task in GovCloud", managed(types.NetworkInterfaceTypeInterface, "578734482556",
+			"arn:aws-us-gov:ecs:us-gov-west-1:123456789012:attachment/e3a1d4c2"), ServiceECS,
+			"arn:aws-us-gov:ecs:us-gov-west-1:123456789012:attachment/e3a1d4c2"},
+		{"other ARN", managed(types.NetworkInterfaceTypeInterface, "123456789012",
+			"arn:aws:lambda:us-east-1:123456789012:function:ecs"), ServiceUnknown, "eni-1"},
+		{"Lambda", managed(types.NetworkInterfaceTypeLambda, "123456789012",
+			"AWS Lambda VPC ENI-my-function-0b9a5a36-1f39-4b5e-9b4e-9f7d0a3c8e21"), ServiceLambda, "my-function"},
+		{"RDS", managed(types.NetworkInterfaceTypeInterface, "amazon-rds", "RDSNetworkInterface"), ServiceRDS, "eni-1"},
+		{"VPC endpoint", managed(types.NetworkInterfaceTypeVpcEndpoint, "123456789012", "VPC Endpoint Interface vpce-1"), ServiceVPCEndpoint, "vpce-1"},
+		{"Global Accelerator", managed(types.NetworkInterfaceTypeGlobalAcceleratorManaged, "123456789012", "AWS Global Accelerator"),
+			ServiceGlobalAccelerator, "eni-1"},
+		{"Transfer Family", managed(types.NetworkInterfaceTypeInterface, "123456789012", "AWS Transfer Family server s-1"), ServiceTransferFamily, "eni-1"},
+		{"OpenSearch", managed(types.NetworkInterfaceTypeInterface, "amazon-elasticsearch", "ES logs"), ServiceOpenSearch, "logs"},
+		{"unknown", managed(types.NetworkInterfaceTypeInterface, "123456789012", "something else"), ServiceUnknown, "eni-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, ownerID := classifyENI(tt.eni)
+			if service != tt.wantService || ownerID != tt.wantOwnerID {
+				t.Errorf("classifyENI() = %q, %q, want %q, %q", service, ownerID, tt.wantService, tt.wantOwnerID)
+			}
+		})
+	}
+}
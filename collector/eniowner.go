@@ -0,0 +1,187 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Services owning the ENIs with public IPs
+const (
+	ServiceEC2Instance       = "EC2 Instance"
+	ServiceALB               = "Application Load Balancer"
+	ServiceNLB               = "Network Load Balancer"
+	ServiceGWLB              = "Gateway Load Balancer"
+	ServiceClassicELB        = "Classic Load Balancer"
+	ServiceNATGateway        = "NAT Gateway"
+	ServiceECS               = "ECS Task"
+	ServiceLambda            = "Lambda"
+	ServiceRDS               = "RDS"
+	ServiceVPCEndpoint       = "VPC Endpoint"
+	ServiceGlobalAccelerator = "Global Accelerator"
+	ServiceTransferFamily    = "Transfer Family"
+	ServiceTransitGateway    = "Transit Gateway"
+	ServiceAPIGateway        = "API Gateway"
+	ServiceDirectoryService  = "Directory Service"
+	ServiceElastiCache       = "ElastiCache"
+	ServiceRedshift          = "Redshift"
+	ServiceOpenSearch        = "OpenSearch"
+	ServiceEKS               = "EKS"
+	ServiceUnattached        = "Unattached"
+	ServiceUnknown           = "Unknown"
+)
+
+// Owning services of the ENI types that identify them on their own
+var eniInterfaceTypes = map[types.NetworkInterfaceType]string{
+	types.NetworkInterfaceTypeNatGateway:                  ServiceNATGateway,
+	types.NetworkInterfaceTypeNetworkLoadBalancer:         ServiceNLB,
+	types.NetworkInterfaceTypeGatewayLoadBalancer:         ServiceGWLB,
+	types.NetworkInterfaceTypeGatewayLoadBalancerEndpoint: ServiceVPCEndpoint,
+	types.NetworkInterfaceTypeVpcEndpoint:                 ServiceVPCEndpoint,
+	types.NetworkInterfaceTypeLambda:                      ServiceLambda,
+	types.NetworkInterfaceTypeTransitGateway:              ServiceTransitGateway,
+	types.NetworkInterfaceTypeApiGatewayManaged:           ServiceAPIGateway,
+	types.NetworkInterfaceTypeGlobalAcceleratorManaged:    ServiceGlobalAccelerator,
+	types.NetworkInterfaceTypeBranch:                      ServiceECS,
+}
+
+// Owning services of the requester managed ENIs, by the prefix of their
+// description, checked in order
+var eniDescriptionPrefixes = []struct {
+	prefix  string
+	service string
+}{
+	{"ELB app/", ServiceALB},
+	{"ELB net/", ServiceNLB},
+	{"ELB gwy/", ServiceGWLB},
+	{"ELB ", ServiceClassicELB},
+	{"Interface for NAT Gateway ", ServiceNATGateway},
+	{"AWS Lambda VPC ENI", ServiceLambda},
+	{"RDSNetworkInterface", ServiceRDS},
+	{"VPC Endpoint Interface ", ServiceVPCEndpoint},
+	{"AWS Global Accelerator", ServiceGlobalAccelerator},
+	{"AWS Transfer", ServiceTransferFamily},
+	{"AWS created network interface for directory ", ServiceDirectoryService},
+	{"ElastiCache", ServiceElastiCache},
+	{"Amazon EKS", ServiceEKS},
+	{"ES ", ServiceOpenSearch},
+}
+
+// Owning services of the requester managed ENIs, by their requester
+var eniRequesters = map[string]string{
+	"amazon-elb":           ServiceClassicELB,
+	"amazon-rds":           ServiceRDS,
+	"amazon-elasticache":   ServiceElastiCache,
+	"amazon-redshift":      ServiceRedshift,
+	"amazon-elasticsearch": ServiceOpenSearch,
+}
+
+// classifyENI returns the service owning an ENI and the ID of the owning
+// resource, from its type, requester, description and attachment. The ENI ID
+// is returned when the owning resource can't be told.
+func classifyENI(eni types.NetworkInterface) (service, ownerID string) {
+	eniID := aws.ToString(eni.NetworkInterfaceId)
+	description := aws.ToString(eni.Description)
+
+	service, ok := eniInterfaceTypes[eni.InterfaceType]
+	if !ok && aws.ToBool(eni.RequesterManaged) {
+		for _, p := range eniDescriptionPrefixes {
+			if strings.HasPrefix(description, p.prefix) {
+				service, ok = p.service, true
+				break
+			}
+		}
+		if !ok && isECSARN(description) {
+			service, ok = ServiceECS, true
+		}
+		if !ok {
+			service, ok = eniRequesters[aws.ToString(eni.RequesterId)]
+		}
+	}
+	if !ok {
+		switch {
+		case eni.Attachment != nil && aws.ToString(eni.Attachment.InstanceId) != "":
+			service = ServiceEC2Instance
+		case eni.Attachment == nil && eni.Status == types.NetworkInterfaceStatusAvailable:
+			service = ServiceUnattached
+		default:
+			service = ServiceUnknown
+		}
+	}
+
+	return service, eniOwnerID(service, eni, description, eniID)
+}
+
+// isECSARN tells whether the description is the ARN of an ECS resource, in any
+// partition, such as arn:aws-cn:ecs:cn-north-1:123456789012:attachment/<id>.
+func isECSARN(description string) bool {
+	parts := strings.SplitN(description, ":", 4)
+	return len(parts) == 4 && parts[0] == "arn" && parts[2] == "ecs"
+}
+
+// The "-<uuid>" suffix of the Lambda ENI descriptions
+const lambdaENISuffix = "-00000000-0000-0000-0000-000000000000"
+
+// eniOwnerID extracts the ID of the resource owning an ENI, which is usually
+// part of its description.
+func eniOwnerID(service string, eni types.NetworkInterface, description, eniID string) string {
+	if eni.Attachment != nil && aws.ToString(eni.Attachment.InstanceId) != "" {
+		return aws.ToString(eni.Attachment.InstanceId)
+	}
+
+	switch service {
+	case ServiceALB, ServiceNLB, ServiceGWLB, ServiceClassicELB:
+		// ELB app/<name>/<id>, or ELB <name> for the classic ones
+		if name, ok := strings.CutPrefix(description, "ELB "); ok {
+			return name
+		}
+	case ServiceNATGateway:
+		if id, ok := strings.CutPrefix(description, "Interface for NAT Gateway "); ok {
+			return id
+		}
+	case ServiceECS:
+		// The ARN of the task attachment
+		if isECSARN(description) {
+			return description
+		}
+	case ServiceLambda:
+		// AWS Lambda VPC ENI-<function name>-<uuid>
+		if name, ok := strings.CutPrefix(description, "AWS Lambda VPC ENI-"); ok {
+			if len(name) > len(lambdaENISuffix) {
+				return name[:len(name)-len(lambdaENISuffix)]
+			}
+			return name
+		}
+	case ServiceVPCEndpoint:
+		if id, ok := strings.CutPrefix(description, "VPC Endpoint Interface "); ok {
+			return id
+		}
+	case ServiceOpenSearch:
+		// ES <domain name>
+		if name, ok := strings.CutPrefix(description, "ES "); ok {
+			return name
+		}
+	case ServiceDirectoryService:
+		if id, ok := strings.CutPrefix(description, "AWS created network interface for directory "); ok {
+			return id
+		}
+	}
+	return eniID
+}
@@ -0,0 +1,398 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
+	elbtypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing/types"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/globalaccelerator"
+	gatypes "github.com/aws/aws-sdk-go-v2/service/globalaccelerator/types"
+	"github.com/aws/aws-sdk-go-v2/service/opensearch"
+	opensearchtypes "github.com/aws/aws-sdk-go-v2/service/opensearch/types"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdstypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/aws/aws-sdk-go-v2/service/redshift"
+	redshifttypes "github.com/aws/aws-sdk-go-v2/service/redshift/types"
+)
+
+// page returns the page of items starting at the offset encoded in token,
+// and the token of the next page if there is one. A pageSize of 0 returns
+// all the items in a single page.
+func page[T any](items []T, token *string, pageSize int) ([]T, *string) {
+	start, _ := strconv.Atoi(aws.ToString(token))
+	if pageSize == 0 || start+pageSize >= len(items) {
+		return items[start:], nil
+	}
+	return items[start : start+pageSize], aws.String(strconv.Itoa(start + pageSize))
+}
+
+type fakeEC2 struct {
+	pageSize int
+
+	regions           []types.Region
+	reservations      []types.Reservation
+	networkInterfaces []types.NetworkInterface
+	addresses         []types.Address
+	natGateways       []types.NatGateway
+	vpnConnections    []types.VpnConnection
+	clientVPNs        []types.ClientVpnEndpoint
+	securityGroups    []types.SecurityGroup
+	subnets           []types.Subnet
+	err               error
+
+	modifiedSubnets []*ec2.ModifySubnetAttributeInput
+	// calls counts the requests made to each API operation
+	calls map[string]int
+}
+
+func (f *fakeEC2) called(operation string) {
+	if f.calls == nil {
+		f.calls = map[string]int{}
+	}
+	f.calls[operation]++
+}
+
+func (f *fakeEC2) DescribeRegions(ctx context.Context, params *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error) {
+	if aws.ToBool(params.AllRegions) {
+		return &ec2.DescribeRegionsOutput{Regions: f.regions}, f.err
+	}
+
+	var regions []types.Region
+	for _, region := range f.regions {
+		if aws.ToString(region.OptInStatus) != OptInStatusNotOptedIn {
+			regions = append(regions, region)
+		}
+	}
+	return &ec2.DescribeRegionsOutput{Regions: regions}, f.err
+}
+
+func (f *fakeEC2) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	reservations, next := page(f.reservations, params.NextToken, f.pageSize)
+	return &ec2.DescribeInstancesOutput{Reservations: reservations, NextToken: next}, f.err
+}
+
+func (f *fakeEC2) DescribeNetworkInterfaces(ctx context.Context, params *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	f.called("DescribeNetworkInterfaces")
+	networkInterfaces, next := page(f.networkInterfaces, params.NextToken, f.pageSize)
+	return &ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: networkInterfaces, NextToken: next}, f.err
+}
+
+func (f *fakeEC2) DescribeAddresses(ctx context.Context, params *ec2.DescribeAddressesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeAddressesOutput, error) {
+	f.called("DescribeAddresses")
+	return &ec2.DescribeAddressesOutput{Addresses: f.addresses}, f.err
+}
+
+func (f *fakeEC2) DescribeNatGateways(ctx context.Context, params *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+	f.called("DescribeNatGateways")
+	natGateways, next := page(f.natGateways, params.NextToken, f.pageSize)
+	return &ec2.DescribeNatGatewaysOutput{NatGateways: natGateways, NextToken: next}, f.err
+}
+
+func (f *fakeEC2) DescribeVpnConnections(ctx context.Context, params *ec2.DescribeVpnConnectionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpnConnectionsOutput, error) {
+	return &ec2.DescribeVpnConnectionsOutput{VpnConnections: f.vpnConnections}, f.err
+}
+
+func (f *fakeEC2) DescribeClientVpnEndpoints(ctx context.Context, params *ec2.DescribeClientVpnEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeClientVpnEndpointsOutput, error) {
+	endpoints, next := page(f.clientVPNs, params.NextToken, f.pageSize)
+	return &ec2.DescribeClientVpnEndpointsOutput{ClientVpnEndpoints: endpoints, NextToken: next}, f.err
+}
+
+func (f *fakeEC2) DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	securityGroups, next := page(f.securityGroups, params.NextToken, f.pageSize)
+	return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: securityGroups, NextToken: next}, f.err
+}
+
+func (f *fakeEC2) DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	subnets, next := page(f.subnets, params.NextToken, f.pageSize)
+	return &ec2.DescribeSubnetsOutput{Subnets: subnets, NextToken: next}, f.err
+}
+
+func (f *fakeEC2) ModifySubnetAttribute(ctx context.Context, params *ec2.ModifySubnetAttributeInput, optFns ...func(*ec2.Options)) (*ec2.ModifySubnetAttributeOutput, error) {
+	f.modifiedSubnets = append(f.modifiedSubnets, params)
+	return &ec2.ModifySubnetAttributeOutput{}, f.err
+}
+
+type fakeELB struct {
+	pageSize      int
+	loadBalancers []elbtypes.LoadBalancerDescription
+	err           error
+}
+
+func (f *fakeELB) DescribeLoadBalancers(ctx context.Context, params *elb.DescribeLoadBalancersInput, optFns ...func(*elb.Options)) (*elb.DescribeLoadBalancersOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	loadBalancers, next := page(f.loadBalancers, params.Marker, f.pageSize)
+	return &elb.DescribeLoadBalancersOutput{LoadBalancerDescriptions: loadBalancers, NextMarker: next}, f.err
+}
+
+type fakeELBv2 struct {
+	pageSize        int
+	loadBalancers   []elbv2types.LoadBalancer
+	targetGroups    []elbv2types.TargetGroup
+	err             error
+	targetGroupsErr error
+}
+
+func (f *fakeELBv2) DescribeLoadBalancers(ctx context.Context, params *elbv2.DescribeLoadBalancersInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeLoadBalancersOutput, error) {
+	loadBalancers, next := page(f.loadBalancers, params.Marker, f.pageSize)
+	return &elbv2.DescribeLoadBalancersOutput{LoadBalancers: loadBalancers, NextMarker: next}, f.err
+}
+
+func (f *fakeELBv2) DescribeTargetGroups(ctx context.Context, params *elbv2.DescribeTargetGroupsInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeTargetGroupsOutput, error) {
+	if f.targetGroupsErr != nil {
+		return nil, f.targetGroupsErr
+	}
+	targetGroups, next := page(f.targetGroups, params.Marker, f.pageSize)
+	return &elbv2.DescribeTargetGroupsOutput{TargetGroups: targetGroups, NextMarker: next}, f.err
+}
+
+// fakeCloudWatch returns the values configured for each metric, by its
+// dimension values and name such as "app/my-alb/123 ProcessedBytes", pageSize
+// values of each metric at a time when it's set.
+type fakeCloudWatch struct {
+	pageSize int
+	values   map[string][]float64
+	err      error
+
+	inputs []*cloudwatch.GetMetricDataInput
+}
+
+func (f *fakeCloudWatch) GetMetricData(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+	f.inputs = append(f.inputs, params)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	out := &cloudwatch.GetMetricDataOutput{}
+	for _, query := range params.MetricDataQueries {
+		metric := query.MetricStat.Metric
+		var key []string
+		for _, dimension := range metric.Dimensions {
+			key = append(key, aws.ToString(dimension.Value))
+		}
+		values := f.values[strings.Join(append(key, aws.ToString(metric.MetricName)), " ")]
+		// The metrics with fewer values run out of them before the others
+		var next *string
+		if start, _ := strconv.Atoi(aws.ToString(params.NextToken)); start < len(values) {
+			values, next = page(values, params.NextToken, f.pageSize)
+		} else {
+			values = nil
+		}
+		if next != nil {
+			out.NextToken = next
+		}
+		out.MetricDataResults = append(out.MetricDataResults, cwtypes.MetricDataResult{
+			Id:     query.Id,
+			Values: values,
+		})
+	}
+	return out, nil
+}
+
+// fakeGlobalAccelerator returns the listeners configured for each accelerator
+// and the endpoint groups for each listener, by their ARNs.
+type fakeGlobalAccelerator struct {
+	pageSize                    int
+	accelerators                []gatypes.Accelerator
+	customRoutingAccelerators   []gatypes.CustomRoutingAccelerator
+	listeners                   map[string][]gatypes.Listener
+	customRoutingListeners      map[string][]gatypes.CustomRoutingListener
+	endpointGroups              map[string][]gatypes.EndpointGroup
+	customRoutingEndpointGroups map[string][]gatypes.CustomRoutingEndpointGroup
+	byoipCidrs                  []gatypes.ByoipCidr
+	err                         error
+	listenersErr                error
+}
+
+func (f *fakeGlobalAccelerator) ListAccelerators(ctx context.Context, params *globalaccelerator.ListAcceleratorsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListAcceleratorsOutput, error) {
+	accelerators, next := page(f.accelerators, params.NextToken, f.pageSize)
+	return &globalaccelerator.ListAcceleratorsOutput{Accelerators: accelerators, NextToken: next}, f.err
+}
+
+func (f *fakeGlobalAccelerator) ListCustomRoutingAccelerators(ctx context.Context, params *globalaccelerator.ListCustomRoutingAcceleratorsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListCustomRoutingAcceleratorsOutput, error) {
+	accelerators, next := page(f.customRoutingAccelerators, params.NextToken, f.pageSize)
+	return &globalaccelerator.ListCustomRoutingAcceleratorsOutput{Accelerators: accelerators, NextToken: next}, f.err
+}
+
+func (f *fakeGlobalAccelerator) ListListeners(ctx context.Context, params *globalaccelerator.ListListenersInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListListenersOutput, error) {
+	if f.listenersErr != nil {
+		return nil, f.listenersErr
+	}
+	listeners, next := page(f.listeners[aws.ToString(params.AcceleratorArn)], params.NextToken, f.pageSize)
+	return &globalaccelerator.ListListenersOutput{Listeners: listeners, NextToken: next}, f.err
+}
+
+func (f *fakeGlobalAccelerator) ListCustomRoutingListeners(ctx context.Context, params *globalaccelerator.ListCustomRoutingListenersInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListCustomRoutingListenersOutput, error) {
+	listeners, next := page(f.customRoutingListeners[aws.ToString(params.AcceleratorArn)], params.NextToken, f.pageSize)
+	return &globalaccelerator.ListCustomRoutingListenersOutput{Listeners: listeners, NextToken: next}, f.err
+}
+
+func (f *fakeGlobalAccelerator) ListEndpointGroups(ctx context.Context, params *globalaccelerator.ListEndpointGroupsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListEndpointGroupsOutput, error) {
+	endpointGroups, next := page(f.endpointGroups[aws.ToString(params.ListenerArn)], params.NextToken, f.pageSize)
+	return &globalaccelerator.ListEndpointGroupsOutput{EndpointGroups: endpointGroups, NextToken: next}, f.err
+}
+
+func (f *fakeGlobalAccelerator) ListCustomRoutingEndpointGroups(ctx context.Context, params *globalaccelerator.ListCustomRoutingEndpointGroupsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListCustomRoutingEndpointGroupsOutput, error) {
+	endpointGroups, next := page(f.customRoutingEndpointGroups[aws.ToString(params.ListenerArn)], params.NextToken, f.pageSize)
+	return &globalaccelerator.ListCustomRoutingEndpointGroupsOutput{EndpointGroups: endpointGroups, NextToken: next}, f.err
+}
+
+func (f *fakeGlobalAccelerator) ListByoipCidrs(ctx context.Context, params *globalaccelerator.ListByoipCidrsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListByoipCidrsOutput, error) {
+	byoipCidrs, next := page(f.byoipCidrs, params.NextToken, f.pageSize)
+	return &globalaccelerator.ListByoipCidrsOutput{ByoipCidrs: byoipCidrs, NextToken: next}, f.err
+}
+
+type fakeRDS struct {
+	pageSize    int
+	dbInstances []rdstypes.DBInstance
+	dbClusters  []rdstypes.DBCluster
+	err         error
+}
+
+func (f *fakeRDS) DescribeDBInstances(ctx context.Context, params *rds.DescribeDBInstancesInput, optFns ...func(*rds.Options)) (*rds.DescribeDBInstancesOutput, error) {
+	dbInstances, next := page(f.dbInstances, params.Marker, f.pageSize)
+	return &rds.DescribeDBInstancesOutput{DBInstances: dbInstances, Marker: next}, f.err
+}
+
+func (f *fakeRDS) DescribeDBClusters(ctx context.Context, params *rds.DescribeDBClustersInput, optFns ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error) {
+	dbClusters, next := page(f.dbClusters, params.Marker, f.pageSize)
+	return &rds.DescribeDBClustersOutput{DBClusters: dbClusters, Marker: next}, f.err
+}
+
+type fakeRedshift struct {
+	pageSize int
+	clusters []redshifttypes.Cluster
+	err      error
+}
+
+func (f *fakeRedshift) DescribeClusters(ctx context.Context, params *redshift.DescribeClustersInput, optFns ...func(*redshift.Options)) (*redshift.DescribeClustersOutput, error) {
+	clusters, next := page(f.clusters, params.Marker, f.pageSize)
+	return &redshift.DescribeClustersOutput{Clusters: clusters, Marker: next}, f.err
+}
+
+// fakeOpenSearch describes the domains configured by their names, listed
+// with their engine types.
+type fakeOpenSearch struct {
+	domains     []opensearchtypes.DomainStatus
+	engineTypes map[string]opensearchtypes.EngineType
+	err         error
+}
+
+func (f *fakeOpenSearch) ListDomainNames(ctx context.Context, params *opensearch.ListDomainNamesInput, optFns ...func(*opensearch.Options)) (*opensearch.ListDomainNamesOutput, error) {
+	out := &opensearch.ListDomainNamesOutput{}
+	for _, domain := range f.domains {
+		out.DomainNames = append(out.DomainNames, opensearchtypes.DomainInfo{
+			DomainName: domain.DomainName,
+			EngineType: f.engineTypes[aws.ToString(domain.DomainName)],
+		})
+	}
+	return out, f.err
+}
+
+func (f *fakeOpenSearch) DescribeDomains(ctx context.Context, params *opensearch.DescribeDomainsInput, optFns ...func(*opensearch.Options)) (*opensearch.DescribeDomainsOutput, error) {
+	out := &opensearch.DescribeDomainsOutput{}
+	for _, domain := range f.domains {
+		for _, name := range params.DomainNames {
+			if aws.ToString(domain.DomainName) == name {
+				out.DomainStatusList = append(out.DomainStatusList, domain)
+			}
+		}
+	}
+	return out, f.err
+}
+
+// fakeClients returns the fake clients configured for each region, and empty
+// ones for the other regions.
+type fakeClients struct {
+	ec2        map[string]*fakeEC2
+	elb        map[string]*fakeELB
+	elbv2      map[string]*fakeELBv2
+	cloudwatch map[string]*fakeCloudWatch
+	rds        map[string]*fakeRDS
+	redshift   map[string]*fakeRedshift
+	opensearch map[string]*fakeOpenSearch
+
+	globalAccelerator *fakeGlobalAccelerator
+}
+
+func (f *fakeClients) EC2(region string) EC2API {
+	if client, ok := f.ec2[region]; ok {
+		return client
+	}
+	return &fakeEC2{}
+}
+
+func (f *fakeClients) ELB(region string) ELBAPI {
+	if client, ok := f.elb[region]; ok {
+		return client
+	}
+	return &fakeELB{}
+}
+
+func (f *fakeClients) ELBv2(region string) ELBv2API {
+	if client, ok := f.elbv2[region]; ok {
+		return client
+	}
+	return &fakeELBv2{}
+}
+
+func (f *fakeClients) CloudWatch(region string) CloudWatchAPI {
+	if client, ok := f.cloudwatch[region]; ok {
+		return client
+	}
+	return &fakeCloudWatch{}
+}
+
+func (f *fakeClients) GlobalAccelerator() GlobalAcceleratorAPI {
+	if f.globalAccelerator != nil {
+		return f.globalAccelerator
+	}
+	return &fakeGlobalAccelerator{}
+}
+
+func (f *fakeClients) RDS(region string) RDSAPI {
+	if client, ok := f.rds[region]; ok {
+		return client
+	}
+	return &fakeRDS{}
+}
+
+func (f *fakeClients) Redshift(region string) RedshiftAPI {
+	if client, ok := f.redshift[region]; ok {
+		return client
+	}
+	return &fakeRedshift{}
+}
+
+func (f *fakeClients) OpenSearch(region string) OpenSearchAPI {
+	if client, ok := f.opensearch[region]; ok {
+		return client
+	}
+	return &fakeOpenSearch{}
+}
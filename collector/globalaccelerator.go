@@ -0,0 +1,372 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/globalaccelerator"
+	"github.com/aws/aws-sdk-go-v2/service/globalaccelerator/types"
+)
+
+// GlobalAcceleratorInfo is a standard or custom routing accelerator with the
+// static IPv4 addresses of its IP sets, which are charged in the home region
+// of Global Accelerator. Listeners are shown as their protocol and port
+// ranges, and EndpointGroups as their region and endpoint IDs.
+type GlobalAcceleratorInfo struct {
+	Profile        string   `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Account        string   `json:"account" yaml:"account"`
+	Region         string   `json:"region" yaml:"region"`
+	AcceleratorARN string   `json:"accelerator_arn" yaml:"accelerator_arn"`
+	Name           string   `json:"name" yaml:"name"`
+	Type           string   `json:"type" yaml:"type"`
+	Status         string   `json:"status" yaml:"status"`
+	Enabled        bool     `json:"enabled" yaml:"enabled"`
+	IPAddressType  string   `json:"ip_address_type" yaml:"ip_address_type"`
+	DNSName        string   `json:"dns_name" yaml:"dns_name"`
+	IPCount        int      `json:"ip_count" yaml:"ip_count"`
+	PublicIPs      []string `json:"public_ips" yaml:"public_ips"`
+	Listeners      []string `json:"listeners" yaml:"listeners"`
+	EndpointGroups []string `json:"endpoint_groups" yaml:"endpoint_groups"`
+	// UnchargedIPCount are the public IPs of the BYOIP address ranges, which
+	// are left out of the costs
+	UnchargedIPCount int     `json:"uncharged_ip_count" yaml:"uncharged_ip_count"`
+	Cost             float64 `json:"cost" yaml:"cost"`
+}
+
+const (
+	// GlobalAcceleratorRegion is the home region of Global Accelerator, which
+	// serves its API and is where its IPs are charged.
+	GlobalAcceleratorRegion = "us-west-2"
+
+	AcceleratorTypeStandard      = "standard"
+	AcceleratorTypeCustomRouting = "custom-routing"
+
+	globalAcceleratorPageSize = 100
+)
+
+// FetchAllGlobalAccelerators returns the standard and custom routing
+// accelerators of the account, with their listeners and endpoint groups.
+// Global Accelerator is a global service, so it's scanned once whatever the
+// regions, and its errors are reported for its home region. The accelerators
+// listed are still returned along with the errors, including those whose
+// listeners couldn't be listed. The IPs of the BYOIP address ranges aren't
+// charged, unless the ranges can't be listed.
+func (c *Collector) FetchAllGlobalAccelerators(ctx context.Context) ([]GlobalAcceleratorInfo, error) {
+	var infos []GlobalAcceleratorInfo
+	var errs []error
+	byoipCidrs, err := c.fetchGlobalAcceleratorBYOIPCidrs(ctx)
+	if err != nil {
+		errs = append(errs, &RegionError{Account: c.Account, Region: GlobalAcceleratorRegion, Err: err})
+	}
+	for _, acceleratorType := range []string{AcceleratorTypeStandard, AcceleratorTypeCustomRouting} {
+		accelerators, err := c.fetchGlobalAccelerators(ctx, acceleratorType, byoipCidrs)
+		infos = append(infos, accelerators...)
+		if err != nil {
+			errs = append(errs, &RegionError{Account: c.Account, Region: GlobalAcceleratorRegion, Err: err})
+		}
+	}
+	return infos, errors.Join(errs...)
+}
+
+// fetchGlobalAcceleratorBYOIPCidrs returns the address ranges brought to
+// Global Accelerator, whose IPs aren't owned by Amazon.
+func (c *Collector) fetchGlobalAcceleratorBYOIPCidrs(ctx context.Context) ([]netip.Prefix, error) {
+	client := c.clients.GlobalAccelerator()
+
+	var prefixes []netip.Prefix
+	paginator := globalaccelerator.NewListByoipCidrsPaginator(client, &globalaccelerator.ListByoipCidrsInput{
+		MaxResults: aws.Int32(globalAcceleratorPageSize),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the BYOIP address ranges: %w", err)
+		}
+		c.recordScan(GlobalAcceleratorRegion, "ListByoipCidrs", 1, len(resp.ByoipCidrs))
+
+		for _, cidr := range resp.ByoipCidrs {
+			prefix, err := netip.ParsePrefix(aws.ToString(cidr.Cidr))
+			if err != nil {
+				debug.Printf("Invalid BYOIP CIDR %s: %v", aws.ToString(cidr.Cidr), err)
+				continue
+			}
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes, nil
+}
+
+// byoipIPCount counts the IPs within the BYOIP address ranges.
+func byoipIPCount(ips []string, byoipCidrs []netip.Prefix) int {
+	count := 0
+	for _, ip := range ips {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			continue
+		}
+		if slices.ContainsFunc(byoipCidrs, func(prefix netip.Prefix) bool {
+			return prefix.Contains(addr)
+		}) {
+			count++
+		}
+	}
+	return count
+}
+
+func (c *Collector) fetchGlobalAccelerators(ctx context.Context, acceleratorType string, byoipCidrs []netip.Prefix) ([]GlobalAcceleratorInfo, error) {
+	var accelerators []types.Accelerator
+	var err error
+	if acceleratorType == AcceleratorTypeCustomRouting {
+		accelerators, err = c.fetchCustomRoutingAccelerators(ctx)
+	} else {
+		accelerators, err = c.fetchStandardAccelerators(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the %s accelerators: %w", acceleratorType, err)
+	}
+
+	var infos []GlobalAcceleratorInfo
+	var errs []error
+	for _, accelerator := range accelerators {
+		var ips []string
+		for _, ipSet := range accelerator.IpSets {
+			// The IPv6 addresses of the dual-stack accelerators aren't charged
+			if ipSet.IpAddressFamily == "IPv6" {
+				continue
+			}
+			ips = append(ips, ipSet.IpAddresses...)
+		}
+
+		info := GlobalAcceleratorInfo{
+			Profile:        c.Profile,
+			Account:        c.Account,
+			Region:         GlobalAcceleratorRegion,
+			AcceleratorARN: aws.ToString(accelerator.AcceleratorArn),
+			Name:           aws.ToString(accelerator.Name),
+			Type:           acceleratorType,
+			Status:         string(accelerator.Status),
+			Enabled:        aws.ToBool(accelerator.Enabled),
+			IPAddressType:  string(accelerator.IpAddressType),
+			DNSName:        aws.ToString(accelerator.DnsName),
+			IPCount:        len(ips),
+			PublicIPs:      ips,
+		}
+		info.UnchargedIPCount = byoipIPCount(ips, byoipCidrs)
+		info.Cost = c.Pricing.MonthlyCost(GlobalAcceleratorRegion, info.IPCount-info.UnchargedIPCount)
+		if err := c.fetchAcceleratorListeners(ctx, &info); err != nil {
+			errs = append(errs, fmt.Errorf("failed to list the listeners of %s: %w", info.AcceleratorARN, err))
+		}
+		infos = append(infos, info)
+	}
+	return infos, errors.Join(errs...)
+}
+
+func (c *Collector) fetchStandardAccelerators(ctx context.Context) ([]types.Accelerator, error) {
+	var accelerators []types.Accelerator
+	paginator := globalaccelerator.NewListAcceleratorsPaginator(c.clients.GlobalAccelerator(), &globalaccelerator.ListAcceleratorsInput{
+		MaxResults: aws.Int32(globalAcceleratorPageSize),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.recordScan(GlobalAcceleratorRegion, "ListAccelerators", 1, len(resp.Accelerators))
+		accelerators = append(accelerators, resp.Accelerators...)
+	}
+	return accelerators, nil
+}
+
+// fetchCustomRoutingAccelerators returns the custom routing accelerators in
+// the shape of the standard ones.
+func (c *Collector) fetchCustomRoutingAccelerators(ctx context.Context) ([]types.Accelerator, error) {
+	var accelerators []types.Accelerator
+	paginator := globalaccelerator.NewListCustomRoutingAcceleratorsPaginator(c.clients.GlobalAccelerator(), &globalaccelerator.ListCustomRoutingAcceleratorsInput{
+		MaxResults: aws.Int32(globalAcceleratorPageSize),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.recordScan(GlobalAcceleratorRegion, "ListCustomRoutingAccelerators", 1, len(resp.Accelerators))
+
+		for _, accelerator := range resp.Accelerators {
+			accelerators = append(accelerators, types.Accelerator{
+				AcceleratorArn: accelerator.AcceleratorArn,
+				Name:           accelerator.Name,
+				IpAddressType:  accelerator.IpAddressType,
+				Enabled:        accelerator.Enabled,
+				IpSets:         accelerator.IpSets,
+				DnsName:        accelerator.DnsName,
+				Status:         types.AcceleratorStatus(accelerator.Status),
+			})
+		}
+	}
+	return accelerators, nil
+}
+
+// fetchAcceleratorListeners sets the listeners of an accelerator and their
+// endpoint groups.
+func (c *Collector) fetchAcceleratorListeners(ctx context.Context, info *GlobalAcceleratorInfo) error {
+	var listeners []types.Listener
+	var err error
+	if info.Type == AcceleratorTypeCustomRouting {
+		listeners, err = c.fetchCustomRoutingListeners(ctx, info.AcceleratorARN)
+	} else {
+		listeners, err = c.fetchStandardListeners(ctx, info.AcceleratorARN)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, listener := range listeners {
+		info.Listeners = append(info.Listeners, formatListener(listener))
+
+		endpointGroups, err := c.fetchListenerEndpointGroups(ctx, info.Type, aws.ToString(listener.ListenerArn))
+		if err != nil {
+			return fmt.Errorf("failed to list the endpoint groups of %s: %w", aws.ToString(listener.ListenerArn), err)
+		}
+		for _, endpointGroup := range endpointGroups {
+			info.EndpointGroups = append(info.EndpointGroups, formatEndpointGroup(endpointGroup))
+		}
+	}
+	return nil
+}
+
+func (c *Collector) fetchStandardListeners(ctx context.Context, acceleratorARN string) ([]types.Listener, error) {
+	var listeners []types.Listener
+	paginator := globalaccelerator.NewListListenersPaginator(c.clients.GlobalAccelerator(), &globalaccelerator.ListListenersInput{
+		AcceleratorArn: aws.String(acceleratorARN),
+		MaxResults:     aws.Int32(globalAcceleratorPageSize),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.recordScan(GlobalAcceleratorRegion, "ListListeners", 1, len(resp.Listeners))
+		listeners = append(listeners, resp.Listeners...)
+	}
+	return listeners, nil
+}
+
+// fetchCustomRoutingListeners returns the listeners of a custom routing
+// accelerator in the shape of the standard ones, without any protocol.
+func (c *Collector) fetchCustomRoutingListeners(ctx context.Context, acceleratorARN string) ([]types.Listener, error) {
+	var listeners []types.Listener
+	paginator := globalaccelerator.NewListCustomRoutingListenersPaginator(c.clients.GlobalAccelerator(), &globalaccelerator.ListCustomRoutingListenersInput{
+		AcceleratorArn: aws.String(acceleratorARN),
+		MaxResults:     aws.Int32(globalAcceleratorPageSize),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.recordScan(GlobalAcceleratorRegion, "ListCustomRoutingListeners", 1, len(resp.Listeners))
+
+		for _, listener := range resp.Listeners {
+			listeners = append(listeners, types.Listener{ListenerArn: listener.ListenerArn, PortRanges: listener.PortRanges})
+		}
+	}
+	return listeners, nil
+}
+
+func (c *Collector) fetchListenerEndpointGroups(ctx context.Context, acceleratorType, listenerARN string) ([]types.EndpointGroup, error) {
+	client := c.clients.GlobalAccelerator()
+
+	var endpointGroups []types.EndpointGroup
+	if acceleratorType == AcceleratorTypeCustomRouting {
+		paginator := globalaccelerator.NewListCustomRoutingEndpointGroupsPaginator(client, &globalaccelerator.ListCustomRoutingEndpointGroupsInput{
+			ListenerArn: aws.String(listenerARN),
+			MaxResults:  aws.Int32(globalAcceleratorPageSize),
+		})
+		for paginator.HasMorePages() {
+			resp, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			c.recordScan(GlobalAcceleratorRegion, "ListCustomRoutingEndpointGroups", 1, len(resp.EndpointGroups))
+
+			// The custom routing endpoints are shown like the standard ones
+			for _, endpointGroup := range resp.EndpointGroups {
+				standard := types.EndpointGroup{EndpointGroupRegion: endpointGroup.EndpointGroupRegion}
+				for _, endpoint := range endpointGroup.EndpointDescriptions {
+					standard.EndpointDescriptions = append(standard.EndpointDescriptions, types.EndpointDescription{EndpointId: endpoint.EndpointId})
+				}
+				endpointGroups = append(endpointGroups, standard)
+			}
+		}
+		return endpointGroups, nil
+	}
+
+	paginator := globalaccelerator.NewListEndpointGroupsPaginator(client, &globalaccelerator.ListEndpointGroupsInput{
+		ListenerArn: aws.String(listenerARN),
+		MaxResults:  aws.Int32(globalAcceleratorPageSize),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.recordScan(GlobalAcceleratorRegion, "ListEndpointGroups", 1, len(resp.EndpointGroups))
+		endpointGroups = append(endpointGroups, resp.EndpointGroups...)
+	}
+	return endpointGroups, nil
+}
+
+// formatListener returns the protocol of a listener, when it has one, and its
+// port ranges, such as "TCP 80,443" or "1000-2000".
+func formatListener(listener types.Listener) string {
+	var ports []string
+	for _, portRange := range listener.PortRanges {
+		from, to := aws.ToInt32(portRange.FromPort), aws.ToInt32(portRange.ToPort)
+		if from == to {
+			ports = append(ports, strconv.Itoa(int(from)))
+		} else {
+			ports = append(ports, fmt.Sprintf("%d-%d", from, to))
+		}
+	}
+
+	if protocol := string(listener.Protocol); protocol != "" {
+		return protocol + " " + strings.Join(ports, ",")
+	}
+	return strings.Join(ports, ",")
+}
+
+// formatEndpointGroup returns the region of an endpoint group and the IDs of
+// its endpoints, such as "us-east-1: i-123 i-456".
+func formatEndpointGroup(endpointGroup types.EndpointGroup) string {
+	region := aws.ToString(endpointGroup.EndpointGroupRegion)
+	var endpoints []string
+	for _, endpoint := range endpointGroup.EndpointDescriptions {
+		endpoints = append(endpoints, aws.ToString(endpoint.EndpointId))
+	}
+	if len(endpoints) == 0 {
+		return region
+	}
+	return region + ": " + strings.Join(endpoints, " ")
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/globalaccelerator/types"
+)
+
+func portRange(from, to int32) types.PortRange {
+	return types.PortRange{FromPort: aws.Int32(from), ToPort: aws.Int32(to)}
+}
+
+func TestFetchAllGlobalAccelerators(t *testing.T) {
+	c := New(&fakeClients{globalAccelerator: &fakeGlobalAccelerator{
+		pageSize: 1,
+		accelerators: []types.Accelerator{{
+			AcceleratorArn: aws.String("arn:standard"),
+			Name:           aws.String("web"),
+			IpAddressType:  types.IpAddressTypeDualStack,
+			Enabled:        aws.Bool(true),
+			DnsName:        aws.String("a123.awsglobalaccelerator.com"),
+			Status:         types.AcceleratorStatusDeployed,
+			IpSets: []types.IpSet{
+				{IpAddressFamily: "IPv4", IpAddresses: []string{"75.2.0.1", "99.83.0.1"}},
+				{IpAddressFamily: "IPv6", IpAddresses: []string{"2600:9000::1", "2600:9000::2"}},
+			},
+		}},
+		customRoutingAccelerators: []types.CustomRoutingAccelerator{{
+			AcceleratorArn: aws.String("arn:custom"),
+			Name:           aws.String("game"),
+			Status:         types.CustomRoutingAcceleratorStatusInProgress,
+			IpSets:         []types.IpSet{{IpAddressFamily: "IPv4", IpAddresses: []string{"75.2.0.2", "99.83.0.2"}}},
+		}},
+		listeners: map[string][]types.Listener{
+			"arn:standard": {
+				{ListenerArn: aws.String("arn:web"), Protocol: types.ProtocolTcp, PortRanges: []types.PortRange{portRange(80, 80), portRange(443, 443)}},
+				{ListenerArn: aws.String("arn:dns"), Protocol: types.ProtocolUdp, PortRanges: []types.PortRange{portRange(53, 53)}},
+			},
+		},
+		customRoutingListeners: map[string][]types.CustomRoutingListener{
+			"arn:custom": {
+				{ListenerArn: aws.String("arn:ports"), PortRanges: []types.PortRange{portRange(10000, 20000)}},
+			},
+		},
+		endpointGroups: map[string][]types.EndpointGroup{
+			"arn:web": {
+				{EndpointGroupRegion: aws.String("us-east-1"), EndpointDescriptions: []types.EndpointDescription{
+					{EndpointId: aws.String("arn:alb")}, {EndpointId: aws.String("eipalloc-1")},
+				}},
+				{EndpointGroupRegion: aws.String("eu-west-1")},
+			},
+		},
+		customRoutingEndpointGroups: map[string][]types.CustomRoutingEndpointGroup{
+			"arn:ports": {
+				{EndpointGroupRegion: aws.String("ap-south-1"), EndpointDescriptions: []types.CustomRoutingEndpointDescription{{EndpointId: aws.String("subnet-1")}}},
+			},
+		},
+		// The IPs of the BYOIP ranges aren't charged
+		byoipCidrs: []types.ByoipCidr{{Cidr: aws.String("99.83.0.0/24"), State: types.ByoipCidrStateReady}},
+	}})
+
+	accelerators, err := c.FetchAllGlobalAccelerators(context.Background())
+	if err != nil {
+		t.Fatalf("FetchAllGlobalAccelerators() error = %v", err)
+	}
+
+	want := []GlobalAcceleratorInfo{
+		{Region: "us-west-2", AcceleratorARN: "arn:standard", Name: "web", Type: "standard", Status: "DEPLOYED", Enabled: true,
+			IPAddressType: "DUAL_STACK", DNSName: "a123.awsglobalaccelerator.com", IPCount: 2, PublicIPs: []string{"75.2.0.1", "99.83.0.1"},
+			Listeners: []string{"TCP 80,443", "UDP 53"}, EndpointGroups: []string{"us-east-1: arn:alb eipalloc-1", "eu-west-1"},
+			UnchargedIPCount: 1, Cost: 3.65},
+		{Region: "us-west-2", AcceleratorARN: "arn:custom", Name: "game", Type: "custom-routing", Status: "IN_PROGRESS",
+			IPCount: 2, PublicIPs: []string{"75.2.0.2", "99.83.0.2"}, Listeners: []string{"10000-20000"},
+			EndpointGroups: []string{"ap-south-1: subnet-1"}, UnchargedIPCount: 1, Cost: 3.65},
+	}
+	if !reflect.DeepEqual(accelerators, want) {
+		t.Errorf("FetchAllGlobalAccelerators() = %+v, want %+v", accelerators, want)
+	}
+
+	stats := map[string]int{}
+	for _, stat := range c.ScanStats() {
+		stats[stat.Operation] = stat.Pages
+	}
+	// A page for each listener, and an empty last page for the endpoint
+	// groups of the listener without any
+	if stats["ListListeners"] != 2 || stats["ListEndpointGroups"] != 3 || stats["ListCustomRoutingEndpointGroups"] != 1 {
+		t.Errorf("ScanStats() pages = %v", stats)
+	}
+}
+
+func TestFetchAllGlobalAcceleratorsError(t *testing.T) {
+	c := New(&fakeClients{globalAccelerator: &fakeGlobalAccelerator{err: errors.New("AccessDeniedException")}})
+
+	_, err := c.FetchAllGlobalAccelerators(context.Background())
+
+	var regionErr *RegionError
+	if !errors.As(err, &regionErr) || regionErr.Region != GlobalAcceleratorRegion {
+		t.Errorf("FetchAllGlobalAccelerators() error = %v, want a *RegionError for %s", err, GlobalAcceleratorRegion)
+	}
+}
+
+func TestFetchAllGlobalAcceleratorsListenersError(t *testing.T) {
+	c := New(&fakeClients{globalAccelerator: &fakeGlobalAccelerator{
+		accelerators: []types.Accelerator{{
+			AcceleratorArn: aws.String("arn:standard"),
+			IpSets:         []types.IpSet{{IpAddressFamily: "IPv4", IpAddresses: []string{"75.2.0.1", "99.83.0.1"}}},
+		}},
+		customRoutingAccelerators: []types.CustomRoutingAccelerator{{AcceleratorArn: aws.String("arn:custom")}},
+		listenersErr:              errors.New("AccessDeniedException"),
+	}})
+
+	accelerators, err := c.FetchAllGlobalAccelerators(context.Background())
+
+	var regionErr *RegionError
+	if !errors.As(err, &regionErr) {
+		t.Errorf("FetchAllGlobalAccelerators() error = %v, want a *RegionError", err)
+	}
+	// The accelerators are still listed, and charged, without their listeners
+	if len(accelerators) != 2 || accelerators[0].IPCount != 2 || accelerators[0].Cost != 7.30 || accelerators[0].Listeners != nil {
+		t.Errorf("FetchAllGlobalAccelerators() = %+v, want both accelerators without their listeners", accelerators)
+	}
+}
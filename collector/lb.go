@@ -0,0 +1,410 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
+	elbtypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing/types"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+)
+
+// LoadBalancerInfo is an ALB, NLB or Classic ELB, with the public IPs of its
+// ENIs. IPSource tells where the public IPs were found.
+type LoadBalancerInfo struct {
+	Profile  string `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Account  string `json:"account" yaml:"account"`
+	Region   string `json:"region" yaml:"region"`
+	Type     string `json:"type" yaml:"type"`
+	Scheme   string `json:"scheme" yaml:"scheme"`
+	DNSName  string `json:"dns_name" yaml:"dns_name"`
+	IPSource string `json:"ip_source" yaml:"ip_source"`
+	// IPAddressType is ipv4, dualstack or dualstack-without-public-ipv4
+	IPAddressType string `json:"ip_address_type" yaml:"ip_address_type"`
+	// DualStackCandidate is set for the load balancers that could switch to
+	// dualstack-without-public-ipv4 to get rid of their public IPs, with
+	// DualStackNote listing what's missing for it, if anything.
+	DualStackCandidate bool     `json:"dualstack_candidate" yaml:"dualstack_candidate"`
+	DualStackNote      string   `json:"dualstack_note,omitempty" yaml:"dualstack_note,omitempty"`
+	IPCount            int      `json:"ip_count" yaml:"ip_count"`
+	PublicIPs          []string `json:"public_ips" yaml:"public_ips"`
+	// UnchargedIPCount are the public IPs that aren't owned by Amazon, such
+	// as the BYOIP EIPs of NLBs, which are left out of the costs
+	UnchargedIPCount int     `json:"uncharged_ip_count" yaml:"uncharged_ip_count"`
+	Cost             float64 `json:"cost" yaml:"cost"`
+
+	// Utilisation over the lookback window
+	ProcessedBytes        int64 `json:"processed_bytes" yaml:"processed_bytes"`
+	RequestCount          int64 `json:"request_count" yaml:"request_count"`
+	PeakActiveConnections int64 `json:"peak_active_connections" yaml:"peak_active_connections"`
+	NewConnections        int64 `json:"new_connections" yaml:"new_connections"`
+	// HealthyHosts and Targets are averaged over the lookback window
+	HealthyHosts int `json:"healthy_hosts" yaml:"healthy_hosts"`
+	Targets      int `json:"targets" yaml:"targets"`
+	// Cost of the public IPs over the lookback window, per GB processed and
+	// per million requests, or 0 without any traffic
+	CostPerGB              float64 `json:"cost_per_gb" yaml:"cost_per_gb"`
+	CostPerMillionRequests float64 `json:"cost_per_million_requests" yaml:"cost_per_million_requests"`
+}
+
+func (c *Collector) fetchLoadBalancers(ctx context.Context, region string) ([]elbv2types.LoadBalancer, error) {
+	debug.Printf("Fetching ALBs and NLBs...")
+	var lbs []elbv2types.LoadBalancer
+	pages := 0
+
+	paginator := elbv2.NewDescribeLoadBalancersPaginator(c.clients.ELBv2(region), &elbv2.DescribeLoadBalancersInput{})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			debug.Printf("Error fetching ALBs and NLBs: %v", err)
+			return nil, err
+		}
+		pages++
+		lbs = append(lbs, resp.LoadBalancers...)
+	}
+
+	c.recordScan(region, "DescribeLoadBalancers (ELBv2)", pages, len(lbs))
+	debug.Printf("Fetched %d ALBs and NLBs.", len(lbs))
+	return lbs, nil
+}
+
+func (c *Collector) fetchClassicLoadBalancers(ctx context.Context, region string) ([]elbtypes.LoadBalancerDescription, error) {
+	debug.Printf("Fetching Classic ELBs...")
+	var lbs []elbtypes.LoadBalancerDescription
+	pages := 0
+
+	paginator := elb.NewDescribeLoadBalancersPaginator(c.clients.ELB(region), &elb.DescribeLoadBalancersInput{})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			debug.Printf("Error fetching Classic ELBs: %v", err)
+			return nil, err
+		}
+		pages++
+		lbs = append(lbs, resp.LoadBalancerDescriptions...)
+	}
+
+	c.recordScan(region, "DescribeLoadBalancers (Classic)", pages, len(lbs))
+	debug.Printf("Fetched %d Classic ELBs.", len(lbs))
+	return lbs, nil
+}
+
+// Where the public IPs of a load balancer were found
+const (
+	// IPSourceENI is used for the IPs of the load balancer's managed ENIs
+	IPSourceENI = "eni"
+	// IPSourceDNS is used for the IPs resolved from the load balancer's DNS
+	// name, which may only be a subset of them
+	IPSourceDNS = "dns"
+	// IPSourceNone is used for internal load balancers, and for the internet
+	// facing ones without any public IP found
+	IPSourceNone = "none"
+)
+
+const (
+	SchemeInternetFacing = "internet-facing"
+	SchemeInternal       = "internal"
+)
+
+// Types of load balancers
+const (
+	LoadBalancerTypeApplication = "application"
+	LoadBalancerTypeNetwork     = "network"
+	LoadBalancerTypeGateway     = "gateway"
+	LoadBalancerTypeClassic     = "classic"
+)
+
+// IP address types of the load balancers
+const (
+	IPAddressTypeIPv4                       = "ipv4"
+	IPAddressTypeDualStack                  = "dualstack"
+	IPAddressTypeDualStackWithoutPublicIPv4 = "dualstack-without-public-ipv4"
+)
+
+// The requester of the ENIs managed by all the types of load balancers
+const requesterIDELB = "amazon-elb"
+
+// fetchLoadBalancerIPs returns the public IPs of the load balancer ENIs in a
+// region, by the load balancer named in the ENI descriptions, which is
+// "app/<name>/<id>" or "net/<name>/<id>" for ALBs and NLBs, the same as in
+// their ARNs, and just the name for Classic ELBs.
+func (c *Collector) fetchLoadBalancerIPs(ctx context.Context, region string) (map[string][]string, error) {
+	ips := map[string][]string{}
+	pages, items := 0, 0
+
+	paginator := ec2.NewDescribeNetworkInterfacesPaginator(c.clients.EC2(region), &ec2.DescribeNetworkInterfacesInput{
+		Filters: []ec2types.Filter{{Name: aws.String("requester-id"), Values: []string{requesterIDELB}}},
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe the load balancer ENIs: %w", err)
+		}
+		pages++
+		items += len(resp.NetworkInterfaces)
+
+		for _, eni := range resp.NetworkInterfaces {
+			name, ok := strings.CutPrefix(aws.ToString(eni.Description), "ELB ")
+			if !ok || eni.Association == nil || aws.ToString(eni.Association.PublicIp) == "" {
+				continue
+			}
+			ips[name] = append(ips[name], aws.ToString(eni.Association.PublicIp))
+		}
+	}
+
+	c.recordScan(region, "DescribeNetworkInterfaces (ELB)", pages, items)
+	return ips, nil
+}
+
+// lookupIP resolves the DNS names of the load balancers, it's replaced in
+// tests
+var lookupIP = net.LookupIP
+
+// publicIPs returns the public IPs of a load balancer from its ENIs. Only when
+// none are found for an internet facing load balancer and the DNS fallback is
+// enabled, they're resolved from its DNS name instead.
+func (c *Collector) publicIPs(eniIPs map[string][]string, lbType, name, scheme, ipAddressType, dnsName string) ([]string, string) {
+	// Gateway Load Balancers only have private IPs, and neither do the ones
+	// without public IPv4 addresses
+	if scheme == SchemeInternal || lbType == LoadBalancerTypeGateway || ipAddressType == IPAddressTypeDualStackWithoutPublicIPv4 {
+		return nil, IPSourceNone
+	}
+	if ips := eniIPs[name]; len(ips) > 0 {
+		return ips, IPSourceENI
+	}
+	if !c.LoadBalancerDNSFallback {
+		return nil, IPSourceNone
+	}
+
+	debug.Printf("Resolving IPs for DNS name: %s", dnsName)
+	resolved, err := lookupIP(dnsName)
+	if err != nil {
+		log.Printf("Failed to resolve the load balancer %s: %v", dnsName, err)
+		return nil, IPSourceNone
+	}
+	var ips []string
+	for _, ip := range resolved {
+		if ip.To4() != nil {
+			ips = append(ips, ip.String())
+		}
+	}
+	debug.Printf("Resolved %d IPs for DNS name: %s", len(ips), dnsName)
+	if len(ips) == 0 {
+		return nil, IPSourceNone
+	}
+	return ips, IPSourceDNS
+}
+
+// flagDualStackCandidates flags the internet facing ALBs with public IPs,
+// which could switch to dualstack-without-public-ipv4 to get rid of them. It's
+// not supported by the other types of load balancers. All the subnets of the
+// load balancer need an IPv6 CIDR for it, and the ones that don't are listed.
+func (c *Collector) flagDualStackCandidates(ctx context.Context, region string, infos []LoadBalancerInfo, lbSubnets map[int][]string) error {
+	var candidates []int
+	for i, info := range infos {
+		if info.Type == LoadBalancerTypeApplication && info.Scheme == SchemeInternetFacing && info.IPCount > 0 &&
+			info.IPAddressType != IPAddressTypeDualStackWithoutPublicIPv4 {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	subnets, err := c.fetchSubnetsInRegion(ctx, region)
+	if err != nil {
+		return err
+	}
+	ipv6 := map[string]bool{}
+	for _, subnet := range subnets {
+		ipv6[subnet.SubnetID] = subnet.IPv6
+	}
+
+	for _, i := range candidates {
+		infos[i].DualStackCandidate = true
+		var missing []string
+		for _, subnet := range lbSubnets[i] {
+			if !ipv6[subnet] {
+				missing = append(missing, subnet)
+			}
+		}
+		if len(missing) > 0 {
+			infos[i].DualStackNote = "needs IPv6 CIDRs in " + strings.Join(missing, ", ")
+		}
+	}
+	return nil
+}
+
+// FetchAllLoadBalancers returns the ALBs, NLBs and Classic ELBs from all the
+// given regions, with the public IPs of their ENIs and their utilisation over
+// the lookback window. The load balancers are still returned when their
+// Classic ELBs, metrics, target groups or subnets can't be fetched, along with
+// the error.
+func (c *Collector) FetchAllLoadBalancers(ctx context.Context, regions []string) ([]LoadBalancerInfo, error) {
+	var allLBs []LoadBalancerInfo
+	var errs []error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, region := range regions {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+
+			lbs, err := c.fetchRegionLoadBalancers(ctx, region)
+
+			mu.Lock()
+			defer mu.Unlock()
+			allLBs = append(allLBs, lbs...)
+			if err != nil {
+				errs = append(errs, &RegionError{Account: c.Account, Region: region, Err: err})
+			}
+		}(region)
+	}
+	wg.Wait()
+
+	return allLBs, errors.Join(errs...)
+}
+
+func (c *Collector) fetchRegionLoadBalancers(ctx context.Context, region string) ([]LoadBalancerInfo, error) {
+	lbs, err := c.fetchLoadBalancers(ctx, region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch LoadBalancers: %w", err)
+	}
+	eniIPs, err := c.fetchLoadBalancerIPs(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	// The Classic ELBs, target groups, subnets and metrics don't prevent
+	// listing the other load balancers, which are returned along with their
+	// errors
+	var errs []error
+	classicLbs, err := c.fetchClassicLoadBalancers(ctx, region)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to fetch Classic LoadBalancers: %w", err))
+	}
+	targetGroups, err := c.fetchTargetGroups(ctx, region)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	owners, err := c.fetchIPOwners(ctx, region)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	var infos []LoadBalancerInfo
+	var queries []lbMetricQuery
+	// The subnets of each of the load balancers, by their index in infos
+	lbSubnets := map[int][]string{}
+
+	for _, lb := range lbs {
+		// Extract the relevant part of the ARN for ALBs and NLBs
+		lbIdentifier := aws.ToString(lb.LoadBalancerArn)
+		if _, id, ok := strings.Cut(lbIdentifier, "loadbalancer/"); ok {
+			lbIdentifier = id
+		} else {
+			debug.Printf("Invalid ARN format: %s", lbIdentifier)
+		}
+
+		ips, source := c.publicIPs(eniIPs, string(lb.Type), lbIdentifier, string(lb.Scheme), string(lb.IpAddressType), aws.ToString(lb.DNSName))
+		queries = append(queries, lbMetricQueries(len(infos), string(lb.Type), lbIdentifier, targetGroups[aws.ToString(lb.LoadBalancerArn)])...)
+		for _, zone := range lb.AvailabilityZones {
+			lbSubnets[len(infos)] = append(lbSubnets[len(infos)], aws.ToString(zone.SubnetId))
+		}
+		infos = append(infos, LoadBalancerInfo{
+			Profile:       c.Profile,
+			Account:       c.Account,
+			Region:        region,
+			Type:          string(lb.Type),
+			Scheme:        string(lb.Scheme),
+			IPAddressType: string(lb.IpAddressType),
+			DNSName:       aws.ToString(lb.DNSName),
+			IPSource:      source,
+			IPCount:       len(ips),
+			PublicIPs:     ips,
+			// The NLBs can have BYOIP EIPs
+			UnchargedIPCount: owners.uncharged(ips),
+		})
+	}
+
+	for _, lb := range classicLbs {
+		name := aws.ToString(lb.LoadBalancerName)
+		ips, source := c.publicIPs(eniIPs, LoadBalancerTypeClassic, name, aws.ToString(lb.Scheme), IPAddressTypeIPv4, aws.ToString(lb.DNSName))
+		queries = append(queries, lbMetricQueries(len(infos), LoadBalancerTypeClassic, name, nil)...)
+		infos = append(infos, LoadBalancerInfo{
+			Profile: c.Profile,
+			Account: c.Account,
+			Region:  region,
+			Type:    LoadBalancerTypeClassic,
+			Scheme:  aws.ToString(lb.Scheme),
+			// Classic ELBs can't go without public IPv4 addresses
+			IPAddressType: IPAddressTypeIPv4,
+			DNSName:       aws.ToString(lb.DNSName),
+			IPSource:      source,
+			IPCount:       len(ips),
+			PublicIPs:     ips,
+		})
+	}
+	for i := range infos {
+		infos[i].Cost = c.Pricing.MonthlyCost(region, infos[i].IPCount-infos[i].UnchargedIPCount)
+	}
+
+	if err := c.flagDualStackCandidates(ctx, region, infos, lbSubnets); err != nil {
+		errs = append(errs, fmt.Errorf("failed to flag the dual-stack candidates: %w", err))
+	}
+
+	metrics := make([]metricQuery, len(queries))
+	for i, query := range queries {
+		metrics[i] = query.metricQuery
+	}
+	values, err := c.fetchMetrics(ctx, region, metrics)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to get the load balancer metrics: %w", err))
+		return infos, errors.Join(errs...)
+	}
+	for i, query := range queries {
+		query.field.add(&infos[query.lb], values[i])
+	}
+
+	// The IPv4 cost of each load balancer over the lookback window, per unit
+	// of its traffic
+	hours := c.Lookback.Hours()
+	for i := range infos {
+		info := &infos[i]
+		windowCost := c.Pricing.Rate(region) * hours * float64(info.IPCount-info.UnchargedIPCount)
+		if info.ProcessedBytes > 0 {
+			info.CostPerGB = windowCost / (float64(info.ProcessedBytes) / bytesInGB)
+		}
+		if info.RequestCount > 0 {
+			info.CostPerMillionRequests = windowCost / (float64(info.RequestCount) / 1e6)
+		}
+	}
+	return infos, errors.Join(errs...)
+}
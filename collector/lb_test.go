@@ -0,0 +1,408 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elbtypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing/types"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+)
+
+func lbENI(description, publicIP string) types.NetworkInterface {
+	eni := types.NetworkInterface{RequesterId: aws.String(requesterIDELB), Description: aws.String(description)}
+	if publicIP != "" {
+		eni.Association = &types.NetworkInterfaceAssociation{PublicIp: aws.String(publicIP)}
+	}
+	return eni
+}
+
+func TestFetchAllLoadBalancers(t *testing.T) {
+	lookupIP = func(host string) ([]net.IP, error) {
+		t.Errorf("unexpected DNS lookup of %s", host)
+		return nil, nil
+	}
+	defer func() { lookupIP = net.LookupIP }()
+
+	cw := &fakeCloudWatch{pageSize: 1, values: map[string][]float64{
+		"app/my-alb/123 ProcessedBytes":                         {100, 200},
+		"app/my-alb/123 RequestCount":                           {10, 30},
+		"app/my-alb/123 ActiveConnectionCount":                  {5, 9},
+		"app/my-alb/123 NewConnectionCount":                     {2, 3},
+		"app/my-alb/123 targetgroup/web/abc HealthyHostCount":   {2, 2},
+		"app/my-alb/123 targetgroup/web/abc UnHealthyHostCount": {1, 1},
+		"app/my-alb/123 targetgroup/admin/def HealthyHostCount": {1},
+		"net/my-nlb/456 ProcessedBytes":                         {50},
+		"my-clb EstimatedProcessedBytes":                        {1000, 1000, 1000},
+		"my-clb HealthyHostCount":                               {1, 2, 3},
+	}}
+
+	c := New(&fakeClients{
+		ec2: map[string]*fakeEC2{"us-east-1": {pageSize: 2, networkInterfaces: []types.NetworkInterface{
+			lbENI("ELB app/my-alb/123", "1.1.1.1"),
+			lbENI("ELB app/my-alb/123", "1.1.1.2"),
+			lbENI("ELB net/my-nlb/456", "2.2.2.2"),
+			lbENI("ELB my-clb", "3.3.3.3"),
+			lbENI("ELB my-clb", "3.3.3.4"),
+			lbENI("ELB my-clb", "3.3.3.5"),
+			lbENI("ELB app/internal-alb/789", ""),
+			publicENI("eni-instance", "4.4.4.4"),
+		}}},
+		elbv2: map[string]*fakeELBv2{"us-east-1": {pageSize: 1, targetGroups: []elbv2types.TargetGroup{
+			{
+				TargetGroupArn:   aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/web/abc"),
+				LoadBalancerArns: []string{"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/my-alb/123"},
+			},
+			{
+				TargetGroupArn:   aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/admin/def"),
+				LoadBalancerArns: []string{"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/my-alb/123"},
+			},
+		}, loadBalancers: []elbv2types.LoadBalancer{
+			{
+				Type:            elbv2types.LoadBalancerTypeEnumApplication,
+				Scheme:          elbv2types.LoadBalancerSchemeEnumInternetFacing,
+				DNSName:         aws.String("alb.example.com"),
+				LoadBalancerArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/my-alb/123"),
+			},
+			{
+				Type:            elbv2types.LoadBalancerTypeEnumNetwork,
+				Scheme:          elbv2types.LoadBalancerSchemeEnumInternetFacing,
+				DNSName:         aws.String("nlb.example.com"),
+				LoadBalancerArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/my-nlb/456"),
+			},
+			{
+				Type:            elbv2types.LoadBalancerTypeEnumApplication,
+				Scheme:          elbv2types.LoadBalancerSchemeEnumInternal,
+				DNSName:         aws.String("internal.example.com"),
+				LoadBalancerArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/internal-alb/789"),
+			},
+		}}},
+		elb: map[string]*fakeELB{"us-east-1": {loadBalancers: []elbtypes.LoadBalancerDescription{
+			{LoadBalancerName: aws.String("my-clb"), Scheme: aws.String(SchemeInternetFacing), DNSName: aws.String("clb.example.com")},
+		}}},
+		cloudwatch: map[string]*fakeCloudWatch{"us-east-1": cw},
+	})
+
+	lbs, err := c.FetchAllLoadBalancers(context.Background(), []string{"us-east-1"})
+	if err != nil {
+		t.Fatalf("FetchAllLoadBalancers() error = %v", err)
+	}
+
+	tests := map[string]struct {
+		lbType   string
+		ipSource string
+		ipCount  int
+		traffic  int64
+		cost     float64
+	}{
+		"alb.example.com":      {"application", IPSourceENI, 2, 300, 7.30},
+		"nlb.example.com":      {"network", IPSourceENI, 1, 50, 3.65},
+		"clb.example.com":      {"classic", IPSourceENI, 3, 3000, 10.95},
+		"internal.example.com": {"application", IPSourceNone, 0, 0, 0},
+	}
+
+	if len(lbs) != len(tests) {
+		t.Fatalf("FetchAllLoadBalancers() returned %d load balancers, want %d", len(lbs), len(tests))
+	}
+	for _, lb := range lbs {
+		want, ok := tests[lb.DNSName]
+		if !ok {
+			t.Errorf("unexpected load balancer %s", lb.DNSName)
+			continue
+		}
+		if lb.Type != want.lbType || lb.IPSource != want.ipSource || lb.IPCount != want.ipCount || lb.ProcessedBytes != want.traffic || !almostEqual(lb.Cost, want.cost) {
+			t.Errorf("load balancer %s = %+v, want %+v", lb.DNSName, lb, want)
+		}
+
+		switch lb.DNSName {
+		case "alb.example.com":
+			// 2 IPs for the 7 days, over 300 bytes and 40 requests
+			windowCost := 2 * 0.005 * 7 * 24
+			if lb.RequestCount != 40 || lb.PeakActiveConnections != 9 || lb.NewConnections != 5 || lb.HealthyHosts != 3 || lb.Targets != 4 ||
+				!almostEqual(lb.CostPerGB, windowCost/(300.0/bytesInGB)) || !almostEqual(lb.CostPerMillionRequests, windowCost/(40/1e6)) {
+				t.Errorf("load balancer %s metrics = %+v", lb.DNSName, lb)
+			}
+		case "clb.example.com":
+			if lb.HealthyHosts != 2 || lb.Targets != 2 || lb.CostPerMillionRequests != 0 {
+				t.Errorf("load balancer %s metrics = %+v, want 2 healthy hosts on average and no requests", lb.DNSName, lb)
+			}
+		}
+	}
+
+	// One request for all the metrics, with a page for each of the 3 values
+	// of the Classic ELB
+	if len(cw.inputs) != 3 {
+		t.Errorf("GetMetricData called %d times, want 3 pages of a single request for all the load balancers", len(cw.inputs))
+	}
+	for _, query := range cw.inputs[0].MetricDataQueries {
+		metric := query.MetricStat.Metric
+		if aws.ToString(metric.Dimensions[0].Value) == "my-clb" && aws.ToString(metric.MetricName) == "ProcessedBytes" {
+			t.Errorf("classic load balancer metric = ProcessedBytes, want EstimatedProcessedBytes")
+		}
+	}
+}
+
+func TestFetchAllLoadBalancersBatchesMetrics(t *testing.T) {
+	// More than a request worth of the 6 metrics of each Classic ELB
+	const count = maxMetricDataQueries/6 + 1
+	var clbs []elbtypes.LoadBalancerDescription
+	values := map[string][]float64{}
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("clb-%d", i)
+		clbs = append(clbs, elbtypes.LoadBalancerDescription{LoadBalancerName: aws.String(name), DNSName: aws.String(name + ".example.com")})
+		values[name+" EstimatedProcessedBytes"] = []float64{float64(i)}
+	}
+	cw := &fakeCloudWatch{values: values}
+
+	c := New(&fakeClients{
+		elb:        map[string]*fakeELB{"us-east-1": {loadBalancers: clbs}},
+		cloudwatch: map[string]*fakeCloudWatch{"us-east-1": cw},
+	})
+
+	lbs, err := c.FetchAllLoadBalancers(context.Background(), []string{"us-east-1"})
+	if err != nil {
+		t.Fatalf("FetchAllLoadBalancers() error = %v", err)
+	}
+	if len(cw.inputs) != 2 {
+		t.Errorf("GetMetricData called %d times, want 2 for %d load balancers", len(cw.inputs), count)
+	}
+	for _, lb := range lbs {
+		if want := "clb-" + strconv.FormatInt(lb.ProcessedBytes, 10) + ".example.com"; lb.DNSName != want {
+			t.Errorf("load balancer %s traffic = %d, want the one of %s", lb.DNSName, lb.ProcessedBytes, want)
+		}
+	}
+}
+
+func TestFetchAllLoadBalancersMetricsError(t *testing.T) {
+	c := New(&fakeClients{
+		elb: map[string]*fakeELB{"us-east-1": {loadBalancers: []elbtypes.LoadBalancerDescription{
+			{LoadBalancerName: aws.String("my-clb"), DNSName: aws.String("clb.example.com")},
+		}}},
+		cloudwatch: map[string]*fakeCloudWatch{"us-east-1": {err: errors.New("throttled")}},
+	})
+
+	lbs, err := c.FetchAllLoadBalancers(context.Background(), []string{"us-east-1"})
+	var regionErr *RegionError
+	if !errors.As(err, &regionErr) || regionErr.Region != "us-east-1" {
+		t.Errorf("FetchAllLoadBalancers() error = %v, want a RegionError for us-east-1", err)
+	}
+	if len(lbs) != 1 {
+		t.Errorf("FetchAllLoadBalancers() = %+v, want the load balancer without its traffic", lbs)
+	}
+}
+
+func TestFetchAllLoadBalancersTargetGroupsError(t *testing.T) {
+	cloudWatch := &fakeCloudWatch{}
+	c := New(&fakeClients{
+		elb: map[string]*fakeELB{"us-east-1": {loadBalancers: []elbtypes.LoadBalancerDescription{
+			{LoadBalancerName: aws.String("my-clb"), DNSName: aws.String("clb.example.com")},
+		}}},
+		elbv2:      map[string]*fakeELBv2{"us-east-1": {targetGroupsErr: errors.New("AccessDenied")}},
+		cloudwatch: map[string]*fakeCloudWatch{"us-east-1": cloudWatch},
+	})
+
+	lbs, err := c.FetchAllLoadBalancers(context.Background(), []string{"us-east-1"})
+	var regionErr *RegionError
+	if !errors.As(err, &regionErr) || regionErr.Region != "us-east-1" {
+		t.Errorf("FetchAllLoadBalancers() error = %v, want a RegionError for us-east-1", err)
+	}
+	if len(lbs) != 1 {
+		t.Errorf("FetchAllLoadBalancers() = %+v, want the load balancer", lbs)
+	}
+	if len(cloudWatch.inputs) == 0 {
+		t.Error("GetMetricData wasn't called, want the metrics fetched without the target groups")
+	}
+}
+
+func TestFetchAllLoadBalancersClassicError(t *testing.T) {
+	c := New(&fakeClients{
+		elb: map[string]*fakeELB{"us-east-1": {err: errors.New("AccessDenied")}},
+		elbv2: map[string]*fakeELBv2{"us-east-1": {loadBalancers: []elbv2types.LoadBalancer{{
+			Type:            elbv2types.LoadBalancerTypeEnumApplication,
+			Scheme:          elbv2types.LoadBalancerSchemeEnumInternetFacing,
+			DNSName:         aws.String("alb.example.com"),
+			LoadBalancerArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/my-alb/123"),
+		}}}},
+		cloudwatch: map[string]*fakeCloudWatch{"us-east-1": {}},
+	})
+
+	lbs, err := c.FetchAllLoadBalancers(context.Background(), []string{"us-east-1"})
+	var regionErr *RegionError
+	if !errors.As(err, &regionErr) || regionErr.Region != "us-east-1" {
+		t.Errorf("FetchAllLoadBalancers() error = %v, want a RegionError for us-east-1", err)
+	}
+	if len(lbs) != 1 || lbs[0].DNSName != "alb.example.com" {
+		t.Errorf("FetchAllLoadBalancers() = %+v, want the ALB", lbs)
+	}
+}
+
+func TestLoadBalancerDNSFallback(t *testing.T) {
+	lookupIP = func(host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("1.1.1.1"), net.ParseIP("2001:db8::1")}, nil
+	}
+	defer func() { lookupIP = net.LookupIP }()
+
+	newCollector := func() *Collector {
+		return New(&fakeClients{
+			ec2: map[string]*fakeEC2{"us-east-1": {}},
+			elb: map[string]*fakeELB{"us-east-1": {loadBalancers: []elbtypes.LoadBalancerDescription{
+				{LoadBalancerName: aws.String("my-clb"), Scheme: aws.String(SchemeInternetFacing), DNSName: aws.String("clb.example.com")},
+			}}},
+			elbv2:      map[string]*fakeELBv2{"us-east-1": {}},
+			cloudwatch: map[string]*fakeCloudWatch{"us-east-1": {}},
+		})
+	}
+
+	tests := []struct {
+		fallback    bool
+		wantSource  string
+		wantIPCount int
+	}{
+		{false, IPSourceNone, 0},
+		{true, IPSourceDNS, 1},
+	}
+	for _, tt := range tests {
+		c := newCollector()
+		c.LoadBalancerDNSFallback = tt.fallback
+
+		lbs, err := c.FetchAllLoadBalancers(context.Background(), []string{"us-east-1"})
+		if err != nil {
+			t.Fatalf("FetchAllLoadBalancers() error = %v", err)
+		}
+		if len(lbs) != 1 || lbs[0].IPSource != tt.wantSource || lbs[0].IPCount != tt.wantIPCount {
+			t.Errorf("FetchAllLoadBalancers() with fallback %v = %+v, want %d IPs from %s", tt.fallback, lbs, tt.wantIPCount, tt.wantSource)
+		}
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	const epsilon = 1e-9
+	return a-b < epsilon && b-a < epsilon
+}
+
+func TestParseLookback(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"12h0m0s", 12 * time.Hour, false},
+		{"30m", 0, true},
+		{"d", 0, true},
+		{"week", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLookback(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLookback(%q) = %v, %v, want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+		if err == nil && FormatLookback(got) != tt.value {
+			t.Errorf("FormatLookback(%v) = %s, want %s", got, FormatLookback(got), tt.value)
+		}
+	}
+}
+
+func TestGatewayAndDualStackLoadBalancers(t *testing.T) {
+	c := New(&fakeClients{
+		ec2: map[string]*fakeEC2{"us-east-1": {
+			networkInterfaces: []types.NetworkInterface{
+				lbENI("ELB app/ready/1", "1.1.1.1"),
+				lbENI("ELB app/not-ready/2", "2.2.2.2"),
+				lbENI("ELB net/nlb/3", "3.3.3.3"),
+			},
+			subnets: []types.Subnet{
+				{SubnetId: aws.String("subnet-ipv6"), Ipv6CidrBlockAssociationSet: []types.SubnetIpv6CidrBlockAssociation{
+					{Ipv6CidrBlockState: &types.SubnetCidrBlockState{State: types.SubnetCidrBlockStateCodeAssociated}},
+				}},
+				{SubnetId: aws.String("subnet-ipv4")},
+			},
+		}},
+		elbv2: map[string]*fakeELBv2{"us-east-1": {loadBalancers: []elbv2types.LoadBalancer{
+			{
+				Type: elbv2types.LoadBalancerTypeEnumApplication, Scheme: elbv2types.LoadBalancerSchemeEnumInternetFacing,
+				IpAddressType: elbv2types.IpAddressTypeDualstack, DNSName: aws.String("ready.example.com"),
+				LoadBalancerArn:   aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/ready/1"),
+				AvailabilityZones: []elbv2types.AvailabilityZone{{SubnetId: aws.String("subnet-ipv6")}},
+			},
+			{
+				Type: elbv2types.LoadBalancerTypeEnumApplication, Scheme: elbv2types.LoadBalancerSchemeEnumInternetFacing,
+				IpAddressType: elbv2types.IpAddressTypeIpv4, DNSName: aws.String("not-ready.example.com"),
+				LoadBalancerArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/not-ready/2"),
+				AvailabilityZones: []elbv2types.AvailabilityZone{
+					{SubnetId: aws.String("subnet-ipv6")}, {SubnetId: aws.String("subnet-ipv4")},
+				},
+			},
+			{
+				Type: elbv2types.LoadBalancerTypeEnumApplication, Scheme: elbv2types.LoadBalancerSchemeEnumInternetFacing,
+				IpAddressType: IPAddressTypeDualStackWithoutPublicIPv4, DNSName: aws.String("no-ipv4.example.com"),
+				LoadBalancerArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/no-ipv4/4"),
+			},
+			{
+				Type: elbv2types.LoadBalancerTypeEnumNetwork, Scheme: elbv2types.LoadBalancerSchemeEnumInternetFacing,
+				IpAddressType: elbv2types.IpAddressTypeIpv4, DNSName: aws.String("nlb.example.com"),
+				LoadBalancerArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/nlb/3"),
+			},
+			{
+				Type: elbv2types.LoadBalancerTypeEnumGateway, DNSName: aws.String("gwlb.example.com"),
+				LoadBalancerArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/gwy/gwlb/5"),
+			},
+		}}},
+		cloudwatch: map[string]*fakeCloudWatch{"us-east-1": {values: map[string][]float64{
+			"gwy/gwlb/5 ProcessedBytes": {42},
+		}}},
+	})
+
+	lbs, err := c.FetchAllLoadBalancers(context.Background(), []string{"us-east-1"})
+	if err != nil {
+		t.Fatalf("FetchAllLoadBalancers() error = %v", err)
+	}
+
+	type result struct {
+		ipCount   int
+		candidate bool
+		note      string
+	}
+	want := map[string]result{
+		"ready.example.com":     {1, true, ""},
+		"not-ready.example.com": {1, true, "needs IPv6 CIDRs in subnet-ipv4"},
+		"no-ipv4.example.com":   {0, false, ""},
+		"nlb.example.com":       {1, false, ""},
+		"gwlb.example.com":      {0, false, ""},
+	}
+	if len(lbs) != len(want) {
+		t.Fatalf("FetchAllLoadBalancers() returned %d load balancers, want %d", len(lbs), len(want))
+	}
+	for _, lb := range lbs {
+		got := result{lb.IPCount, lb.DualStackCandidate, lb.DualStackNote}
+		if got != want[lb.DNSName] {
+			t.Errorf("load balancer %s = %+v, want %+v", lb.DNSName, got, want[lb.DNSName])
+		}
+		if lb.DNSName == "gwlb.example.com" && lb.ProcessedBytes != 42 {
+			t.Errorf("gateway load balancer traffic = %d, want 42", lb.ProcessedBytes)
+		}
+	}
+}
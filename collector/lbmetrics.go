@@ -0,0 +1,303 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+)
+
+// DefaultLookback is the window the load balancer metrics are fetched for
+const DefaultLookback = 7 * 24 * time.Hour
+
+// The maximum number of queries in a single GetMetricData request
+const maxMetricDataQueries = 500
+
+const bytesInGB = 1024 * 1024 * 1024
+
+// ParseLookback parses a lookback window such as "30d", or any duration
+// accepted by time.ParseDuration, such as "12h".
+func ParseLookback(value string) (time.Duration, error) {
+	var lookback time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid lookback %q", value)
+		}
+		lookback = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if lookback, err = time.ParseDuration(value); err != nil {
+			return 0, fmt.Errorf("invalid lookback %q", value)
+		}
+	}
+
+	if lookback < time.Hour {
+		return 0, fmt.Errorf("lookback %q is shorter than the 1 hour metrics period", value)
+	}
+	return lookback, nil
+}
+
+// FormatLookback returns the lookback window in days when it's a whole number
+// of them, as accepted by ParseLookback.
+func FormatLookback(lookback time.Duration) string {
+	if lookback%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", lookback/(24*time.Hour))
+	}
+	return lookback.String()
+}
+
+// lbMetricField is the LoadBalancerInfo field a metric is added to
+type lbMetricField int
+
+const (
+	fieldProcessedBytes lbMetricField = iota
+	fieldRequestCount
+	fieldActiveConnections
+	fieldNewConnections
+	fieldHealthyHosts
+	fieldUnhealthyHosts
+)
+
+func (f lbMetricField) add(info *LoadBalancerInfo, value float64) {
+	switch f {
+	case fieldProcessedBytes:
+		info.ProcessedBytes += int64(value)
+	case fieldRequestCount:
+		info.RequestCount += int64(value)
+	case fieldActiveConnections:
+		info.PeakActiveConnections += int64(value)
+	case fieldNewConnections:
+		info.NewConnections += int64(value)
+	case fieldHealthyHosts:
+		info.HealthyHosts += int(math.Round(value))
+		info.Targets += int(math.Round(value))
+	case fieldUnhealthyHosts:
+		info.Targets += int(math.Round(value))
+	}
+}
+
+// metricQuery is a CloudWatch metric and the statistic fetched for it
+type metricQuery struct {
+	namespace  string
+	metricName string
+	// stat is Sum for the totals, Maximum for the peaks and Average for the
+	// gauges such as the host counts
+	stat       string
+	dimensions []cwtypes.Dimension
+}
+
+// lbMetricQuery is a CloudWatch metric of the load balancer at index lb
+type lbMetricQuery struct {
+	lb    int
+	field lbMetricField
+	metricQuery
+}
+
+type lbMetricName struct {
+	field      lbMetricField
+	metricName string
+	stat       string
+}
+
+// The metrics of each type of load balancer, along with their namespace and
+// the name of the load balancer dimension
+var lbMetricNames = map[string]struct {
+	namespace string
+	dimension string
+	metrics   []lbMetricName
+}{
+	LoadBalancerTypeApplication: {"AWS/ApplicationELB", "LoadBalancer", []lbMetricName{
+		{fieldProcessedBytes, "ProcessedBytes", "Sum"},
+		{fieldRequestCount, "RequestCount", "Sum"},
+		{fieldActiveConnections, "ActiveConnectionCount", "Maximum"},
+		{fieldNewConnections, "NewConnectionCount", "Sum"},
+	}},
+	LoadBalancerTypeNetwork: {"AWS/NetworkELB", "LoadBalancer", []lbMetricName{
+		{fieldProcessedBytes, "ProcessedBytes", "Sum"},
+		{fieldActiveConnections, "ActiveFlowCount", "Maximum"},
+		{fieldNewConnections, "NewFlowCount", "Sum"},
+	}},
+	LoadBalancerTypeGateway: {"AWS/GatewayELB", "LoadBalancer", []lbMetricName{
+		{fieldProcessedBytes, "ProcessedBytes", "Sum"},
+		{fieldActiveConnections, "ActiveFlowCount", "Maximum"},
+		{fieldNewConnections, "NewFlowCount", "Sum"},
+	}},
+	LoadBalancerTypeClassic: {"AWS/ELB", "LoadBalancerName", []lbMetricName{
+		{fieldProcessedBytes, "EstimatedProcessedBytes", "Sum"},
+		{fieldRequestCount, "RequestCount", "Sum"},
+		{fieldActiveConnections, "EstimatedALBActiveConnectionCount", "Maximum"},
+		{fieldNewConnections, "EstimatedALBNewConnectionCount", "Sum"},
+		{fieldHealthyHosts, "HealthyHostCount", "Average"},
+		{fieldUnhealthyHosts, "UnHealthyHostCount", "Average"},
+	}},
+}
+
+// lbMetricQueries returns the queries of all the metrics of a load balancer.
+// The host counts of ALBs and NLBs are only reported per target group, so
+// they're queried for each of the target groups of the load balancer.
+func lbMetricQueries(lb int, lbType, lbIdentifier string, targetGroups []string) []lbMetricQuery {
+	names, ok := lbMetricNames[lbType]
+	if !ok {
+		return nil
+	}
+
+	var queries []lbMetricQuery
+	for _, metric := range names.metrics {
+		queries = append(queries, lbMetricQuery{lb: lb, field: metric.field, metricQuery: metricQuery{
+			namespace:  names.namespace,
+			metricName: metric.metricName,
+			stat:       metric.stat,
+			dimensions: []cwtypes.Dimension{{Name: aws.String(names.dimension), Value: aws.String(lbIdentifier)}},
+		}})
+	}
+	for _, targetGroup := range targetGroups {
+		for _, metric := range []lbMetricName{
+			{fieldHealthyHosts, "HealthyHostCount", "Average"},
+			{fieldUnhealthyHosts, "UnHealthyHostCount", "Average"},
+		} {
+			queries = append(queries, lbMetricQuery{lb: lb, field: metric.field, metricQuery: metricQuery{
+				namespace:  names.namespace,
+				metricName: metric.metricName,
+				stat:       metric.stat,
+				dimensions: []cwtypes.Dimension{
+					{Name: aws.String(names.dimension), Value: aws.String(lbIdentifier)},
+					{Name: aws.String("TargetGroup"), Value: aws.String(targetGroup)},
+				},
+			}})
+		}
+	}
+	return queries
+}
+
+// fetchTargetGroups returns the target groups of the ALBs and NLBs of a
+// region by load balancer ARN, as "targetgroup/<name>/<id>" like in the
+// CloudWatch dimensions.
+func (c *Collector) fetchTargetGroups(ctx context.Context, region string) (map[string][]string, error) {
+	targetGroups := map[string][]string{}
+	pages, items := 0, 0
+
+	paginator := elbv2.NewDescribeTargetGroupsPaginator(c.clients.ELBv2(region), &elbv2.DescribeTargetGroupsInput{})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe target groups: %w", err)
+		}
+		pages++
+		items += len(resp.TargetGroups)
+
+		for _, tg := range resp.TargetGroups {
+			_, id, ok := strings.Cut(aws.ToString(tg.TargetGroupArn), ":targetgroup/")
+			if !ok {
+				debug.Printf("Invalid ARN format: %s", aws.ToString(tg.TargetGroupArn))
+				continue
+			}
+			for _, lbArn := range tg.LoadBalancerArns {
+				targetGroups[lbArn] = append(targetGroups[lbArn], "targetgroup/"+id)
+			}
+		}
+	}
+
+	c.recordScan(region, "DescribeTargetGroups", pages, items)
+	return targetGroups, nil
+}
+
+// fetchMetrics returns the value of each of the metrics over the lookback
+// window, in the same order as the queries: the sum of the datapoints for the
+// Sum statistic, the highest for Maximum and their mean for Average. The
+// metrics are fetched in as few GetMetricData requests as possible.
+func (c *Collector) fetchMetrics(ctx context.Context, region string, queries []metricQuery) ([]float64, error) {
+	values := make([]float64, len(queries))
+	if len(queries) == 0 {
+		return values, nil
+	}
+	datapoints := make([]int, len(queries))
+
+	cwClient := c.clients.CloudWatch(region)
+	endTime := time.Now()
+	startTime := endTime.Add(-c.Lookback)
+	pages, items := 0, 0
+
+	for start := 0; start < len(queries); start += maxMetricDataQueries {
+		end := min(start+maxMetricDataQueries, len(queries))
+
+		var dataQueries []cwtypes.MetricDataQuery
+		for i, query := range queries[start:end] {
+			dataQueries = append(dataQueries, cwtypes.MetricDataQuery{
+				// The IDs must start with a lowercase letter
+				Id: aws.String(fmt.Sprintf("m%d", start+i)),
+				MetricStat: &cwtypes.MetricStat{
+					Metric: &cwtypes.Metric{
+						Namespace:  aws.String(query.namespace),
+						MetricName: aws.String(query.metricName),
+						Dimensions: query.dimensions,
+					},
+					Period: aws.Int32(3600), // 3600 seconds = 1 hour
+					Stat:   aws.String(query.stat),
+				},
+				ReturnData: aws.Bool(true),
+			})
+		}
+
+		paginator := cloudwatch.NewGetMetricDataPaginator(cwClient, &cloudwatch.GetMetricDataInput{
+			StartTime:         aws.Time(startTime),
+			EndTime:           aws.Time(endTime),
+			MetricDataQueries: dataQueries,
+		})
+		for paginator.HasMorePages() {
+			resp, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get metric data: %w", err)
+			}
+			pages++
+
+			for _, result := range resp.MetricDataResults {
+				var i int
+				if _, err := fmt.Sscanf(aws.ToString(result.Id), "m%d", &i); err != nil || i < 0 || i >= len(queries) {
+					return nil, fmt.Errorf("unexpected metric data result ID %q", aws.ToString(result.Id))
+				}
+				items += len(result.Values)
+				for _, value := range result.Values {
+					if queries[i].stat == "Maximum" {
+						values[i] = math.Max(values[i], value)
+					} else {
+						values[i] += value
+					}
+				}
+				datapoints[i] += len(result.Values)
+			}
+		}
+	}
+
+	for i, query := range queries {
+		if query.stat == "Average" && datapoints[i] > 0 {
+			values[i] /= float64(datapoints[i])
+		}
+	}
+
+	c.recordScan(region, "GetMetricData", pages, items)
+	return values, nil
+}
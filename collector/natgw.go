@@ -0,0 +1,232 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// NATGatewayInfo is a NAT gateway with its public IPs, including the secondary
+// ones, and its utilisation over the lookback window. Cost is the cost of its
+// public IPs only.
+type NATGatewayInfo struct {
+	Profile          string   `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Account          string   `json:"account" yaml:"account"`
+	Region           string   `json:"region" yaml:"region"`
+	NATGatewayID     string   `json:"nat_gateway_id" yaml:"nat_gateway_id"`
+	NameTag          string   `json:"name_tag" yaml:"name_tag"`
+	State            string   `json:"state" yaml:"state"`
+	ConnectivityType string   `json:"connectivity_type" yaml:"connectivity_type"`
+	VPCID            string   `json:"vpc_id" yaml:"vpc_id"`
+	SubnetID         string   `json:"subnet_id" yaml:"subnet_id"`
+	AvailabilityZone string   `json:"availability_zone" yaml:"availability_zone"`
+	IPCount          int      `json:"ip_count" yaml:"ip_count"`
+	PublicIPs        []string `json:"public_ips" yaml:"public_ips"`
+	Cost             float64  `json:"cost" yaml:"cost"`
+
+	// Utilisation over the lookback window
+	BytesOutToDestination int64 `json:"bytes_out_to_destination" yaml:"bytes_out_to_destination"`
+	PeakActiveConnections int64 `json:"peak_active_connections" yaml:"peak_active_connections"`
+
+	// ConsolidationCandidate is set for the NAT gateways of the VPCs with one
+	// in each of several AZs and hardly any traffic, which could share a
+	// single one, with ConsolidationNote telling the monthly savings.
+	ConsolidationCandidate bool   `json:"consolidation_candidate" yaml:"consolidation_candidate"`
+	ConsolidationNote      string `json:"consolidation_note,omitempty" yaml:"consolidation_note,omitempty"`
+}
+
+// NATConsolidationBytesPerDay is the traffic of all the NAT gateways of a VPC
+// below which they're flagged for consolidation
+const NATConsolidationBytesPerDay = bytesInGB
+
+// fetchNATGatewaysInRegion returns the NAT gateways of a region, including
+// those deleted recently. They're fetched once for both the NAT gateway and
+// the EIP collectors.
+func (c *Collector) fetchNATGatewaysInRegion(ctx context.Context, regionName string) ([]types.NatGateway, error) {
+	return fetchShared(c, regionName, "DescribeNatGateways", func() ([]types.NatGateway, error) {
+		return c.describeNATGateways(ctx, regionName)
+	})
+}
+
+func (c *Collector) describeNATGateways(ctx context.Context, regionName string) ([]types.NatGateway, error) {
+	var natGateways []types.NatGateway
+	pages := 0
+
+	paginator := ec2.NewDescribeNatGatewaysPaginator(c.clients.EC2(regionName), &ec2.DescribeNatGatewaysInput{})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe NAT gateways: %w", err)
+		}
+		pages++
+		natGateways = append(natGateways, resp.NatGateways...)
+	}
+
+	c.recordScan(regionName, "DescribeNatGateways", pages, len(natGateways))
+	return natGateways, nil
+}
+
+// FetchAllNATGateways returns the NAT gateways that aren't deleted from all
+// the given regions, with their traffic over the lookback window. The NAT
+// gateways are still returned when their metrics can't be fetched, along with
+// the error.
+func (c *Collector) FetchAllNATGateways(ctx context.Context, regions []string) ([]NATGatewayInfo, error) {
+	var allNATGateways []NATGatewayInfo
+	var errs []error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, region := range regions {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+
+			natGateways, err := c.fetchRegionNATGateways(ctx, region)
+
+			mu.Lock()
+			defer mu.Unlock()
+			allNATGateways = append(allNATGateways, natGateways...)
+			if err != nil {
+				errs = append(errs, &RegionError{Account: c.Account, Region: region, Err: err})
+			}
+		}(region)
+	}
+	wg.Wait()
+
+	return allNATGateways, errors.Join(errs...)
+}
+
+func (c *Collector) fetchRegionNATGateways(ctx context.Context, region string) ([]NATGatewayInfo, error) {
+	natGateways, err := c.fetchNATGatewaysInRegion(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []NATGatewayInfo
+	var queries []metricQuery
+	for _, natGateway := range natGateways {
+		if natGateway.State == types.NatGatewayStateDeleted {
+			continue
+		}
+
+		var ips []string
+		for _, address := range natGateway.NatGatewayAddresses {
+			if ip := aws.ToString(address.PublicIp); ip != "" {
+				ips = append(ips, ip)
+			}
+		}
+
+		id := aws.ToString(natGateway.NatGatewayId)
+		dimensions := []cwtypes.Dimension{{Name: aws.String("NatGatewayId"), Value: aws.String(id)}}
+		queries = append(queries,
+			metricQuery{namespace: "AWS/NATGateway", metricName: "BytesOutToDestination", stat: "Sum", dimensions: dimensions},
+			metricQuery{namespace: "AWS/NATGateway", metricName: "ActiveConnectionCount", stat: "Maximum", dimensions: dimensions},
+		)
+		infos = append(infos, NATGatewayInfo{
+			Profile:          c.Profile,
+			Account:          c.Account,
+			Region:           region,
+			NATGatewayID:     id,
+			NameTag:          getNameTagValue(natGateway.Tags),
+			State:            string(natGateway.State),
+			ConnectivityType: string(natGateway.ConnectivityType),
+			VPCID:            aws.ToString(natGateway.VpcId),
+			SubnetID:         aws.ToString(natGateway.SubnetId),
+			IPCount:          len(ips),
+			PublicIPs:        ips,
+			Cost:             c.Pricing.MonthlyCost(region, len(ips)),
+		})
+	}
+	if len(infos) == 0 {
+		return nil, nil
+	}
+
+	subnets, err := c.fetchSubnetsInRegion(ctx, region)
+	if err != nil {
+		return infos, err
+	}
+	zones := map[string]string{}
+	for _, subnet := range subnets {
+		zones[subnet.SubnetID] = subnet.AvailabilityZone
+	}
+	for i := range infos {
+		infos[i].AvailabilityZone = zones[infos[i].SubnetID]
+	}
+
+	values, err := c.fetchMetrics(ctx, region, queries)
+	if err != nil {
+		return infos, fmt.Errorf("failed to get the NAT gateway metrics: %w", err)
+	}
+	for i := range infos {
+		infos[i].BytesOutToDestination = int64(values[2*i])
+		infos[i].PeakActiveConnections = int64(values[2*i+1])
+	}
+
+	c.flagNATConsolidationCandidates(infos)
+	return infos, nil
+}
+
+// flagNATConsolidationCandidates flags the public NAT gateways of the VPCs
+// that have them in several AZs, when they all moved less than
+// NATConsolidationBytesPerDay over the lookback window. Keeping a single one
+// would save the hourly charges and public IPs of the others, at the cost of
+// cross-AZ traffic and of the NAT gateway being a single point of failure.
+func (c *Collector) flagNATConsolidationCandidates(infos []NATGatewayInfo) {
+	byVPC := map[string][]int{}
+	for i, info := range infos {
+		if info.ConnectivityType == string(types.ConnectivityTypePublic) && info.State == string(types.NatGatewayStateAvailable) {
+			byVPC[info.VPCID] = append(byVPC[info.VPCID], i)
+		}
+	}
+
+	threshold := NATConsolidationBytesPerDay * c.Lookback.Hours() / 24
+	for vpc, indexes := range byVPC {
+		zones := map[string]bool{}
+		var bytes int64
+		var ipCosts []float64
+		for _, i := range indexes {
+			zones[infos[i].AvailabilityZone] = true
+			bytes += infos[i].BytesOutToDestination
+			ipCosts = append(ipCosts, infos[i].Cost)
+		}
+		if len(zones) < 2 || float64(bytes) >= threshold {
+			continue
+		}
+
+		// The savings assume the NAT gateway with the most public IPs is kept
+		sort.Float64s(ipCosts)
+		savings := float64(len(indexes)-1) * c.Pricing.NATGatewayMonthlyCost()
+		for _, cost := range ipCosts[:len(ipCosts)-1] {
+			savings += cost
+		}
+		note := fmt.Sprintf("%d NAT gateways in %s moved %.2f GB in %s, keeping one would save %s per month",
+			len(indexes), vpc, float64(bytes)/bytesInGB, FormatLookback(c.Lookback), c.Pricing.Format(savings))
+		for _, i := range indexes {
+			infos[i].ConsolidationCandidate = true
+			infos[i].ConsolidationNote = note
+		}
+	}
+}
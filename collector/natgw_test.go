@@ -0,0 +1,192 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func natGateway(id, vpc, subnet string, connectivity types.ConnectivityType, state types.NatGatewayState, publicIPs ...string) types.NatGateway {
+	natGateway := types.NatGateway{
+		NatGatewayId:     aws.String(id),
+		VpcId:            aws.String(vpc),
+		SubnetId:         aws.String(subnet),
+		ConnectivityType: connectivity,
+		State:            state,
+	}
+	for i, ip := range publicIPs {
+		natGateway.NatGatewayAddresses = append(natGateway.NatGatewayAddresses, types.NatGatewayAddress{
+			PublicIp:  aws.String(ip),
+			IsPrimary: aws.Bool(i == 0),
+		})
+	}
+	return natGateway
+}
+
+func TestFetchAllNATGateways(t *testing.T) {
+	available := types.NatGatewayStateAvailable
+	c := New(&fakeClients{
+		ec2: map[string]*fakeEC2{"us-east-1": {
+			natGateways: []types.NatGateway{
+				natGateway("nat-a", "vpc-quiet", "subnet-a", types.ConnectivityTypePublic, available, "1.1.1.1", "1.1.1.2"),
+				natGateway("nat-b", "vpc-quiet", "subnet-b", types.ConnectivityTypePublic, available, "2.2.2.2"),
+				natGateway("nat-busy-a", "vpc-busy", "subnet-c", types.ConnectivityTypePublic, available, "3.3.3.3"),
+				natGateway("nat-busy-b", "vpc-busy", "subnet-d", types.ConnectivityTypePublic, available, "4.4.4.4"),
+				natGateway("nat-private", "vpc-quiet", "subnet-b", types.ConnectivityTypePrivate, available),
+				natGateway("nat-deleted", "vpc-quiet", "subnet-a", types.ConnectivityTypePublic, types.NatGatewayStateDeleted),
+			},
+			subnets: []types.Subnet{
+				{SubnetId: aws.String("subnet-a"), AvailabilityZone: aws.String("us-east-1a")},
+				{SubnetId: aws.String("subnet-b"), AvailabilityZone: aws.String("us-east-1b")},
+				{SubnetId: aws.String("subnet-c"), AvailabilityZone: aws.String("us-east-1a")},
+				{SubnetId: aws.String("subnet-d"), AvailabilityZone: aws.String("us-east-1b")},
+			},
+		}},
+		cloudwatch: map[string]*fakeCloudWatch{"us-east-1": {values: map[string][]float64{
+			"nat-a BytesOutToDestination":      {1000, 2000},
+			"nat-a ActiveConnectionCount":      {3, 7, 5},
+			"nat-busy-a BytesOutToDestination": {100 * bytesInGB},
+		}}},
+	})
+
+	natGateways, err := c.FetchAllNATGateways(context.Background(), []string{"us-east-1"})
+	if err != nil {
+		t.Fatalf("FetchAllNATGateways() error = %v", err)
+	}
+
+	got := map[string]NATGatewayInfo{}
+	for _, natGateway := range natGateways {
+		got[natGateway.NATGatewayID] = natGateway
+	}
+	if len(got) != 5 {
+		t.Fatalf("FetchAllNATGateways() = %+v, want all the NAT gateways but the deleted one", natGateways)
+	}
+
+	a := got["nat-a"]
+	if !reflect.DeepEqual(a.PublicIPs, []string{"1.1.1.1", "1.1.1.2"}) || a.IPCount != 2 || !almostEqual(a.Cost, 7.3) {
+		t.Errorf("nat-a = %+v, want its primary and secondary public IPs", a)
+	}
+	if a.AvailabilityZone != "us-east-1a" || a.BytesOutToDestination != 3000 || a.PeakActiveConnections != 7 {
+		t.Errorf("nat-a = %+v, want its AZ and metrics", a)
+	}
+
+	// The NAT gateway hours and the IPv4 of nat-b
+	wantSavings := c.Pricing.Format(c.Pricing.NATGatewayMonthlyCost() + c.Pricing.MonthlyCost("us-east-1", 1))
+	for _, id := range []string{"nat-a", "nat-b"} {
+		if !got[id].ConsolidationCandidate || !strings.Contains(got[id].ConsolidationNote, wantSavings) {
+			t.Errorf("%s = %+v, want a consolidation candidate saving %s", id, got[id], wantSavings)
+		}
+	}
+	for _, id := range []string{"nat-busy-a", "nat-busy-b", "nat-private"} {
+		if got[id].ConsolidationCandidate {
+			t.Errorf("%s = %+v, want it not flagged", id, got[id])
+		}
+	}
+}
+
+func TestFetchAllNATGatewaysMetricsError(t *testing.T) {
+	c := New(&fakeClients{
+		ec2: map[string]*fakeEC2{"us-east-1": {natGateways: []types.NatGateway{
+			natGateway("nat-a", "vpc-1", "subnet-a", types.ConnectivityTypePublic, types.NatGatewayStateAvailable, "1.1.1.1"),
+		}}},
+		cloudwatch: map[string]*fakeCloudWatch{"us-east-1": {err: errors.New("throttled")}},
+	})
+
+	natGateways, err := c.FetchAllNATGateways(context.Background(), []string{"us-east-1"})
+
+	var regionErr *RegionError
+	if !errors.As(err, &regionErr) || regionErr.Region != "us-east-1" {
+		t.Errorf("FetchAllNATGateways() error = %v, want a *RegionError for us-east-1", err)
+	}
+	if len(natGateways) != 1 {
+		t.Errorf("FetchAllNATGateways() = %+v, want the NAT gateway without its metrics", natGateways)
+	}
+}
+
+func TestNATGatewaysScannedOnce(t *testing.T) {
+	nat := natGateway("nat-a", "vpc-1", "subnet-a", types.ConnectivityTypePublic, types.NatGatewayStateAvailable, "1.1.1.1")
+	nat.NatGatewayAddresses[0].AllocationId = aws.String("eipalloc-nat")
+	ec2Client := &fakeEC2{
+		natGateways: []types.NatGateway{nat},
+		addresses: []types.Address{{
+			PublicIp:           aws.String("1.1.1.1"),
+			AllocationId:       aws.String("eipalloc-nat"),
+			AssociationId:      aws.String("eipassoc-nat"),
+			NetworkInterfaceId: aws.String("eni-nat"),
+		}},
+		subnets: []types.Subnet{{SubnetId: aws.String("subnet-a"), AvailabilityZone: aws.String("us-east-1a")}},
+	}
+	c := New(&fakeClients{ec2: map[string]*fakeEC2{"us-east-1": ec2Client}})
+	regions := []string{"us-east-1"}
+
+	if _, err := c.FetchAllEIPs(context.Background(), regions); err != nil {
+		t.Fatalf("FetchAllEIPs() error = %v", err)
+	}
+	if _, err := c.FetchAllNATGateways(context.Background(), regions); err != nil {
+		t.Fatalf("FetchAllNATGateways() error = %v", err)
+	}
+	if _, err := c.FetchAllSubnets(context.Background(), regions); err != nil {
+		t.Fatalf("FetchAllSubnets() error = %v", err)
+	}
+
+	if calls := ec2Client.calls["DescribeNatGateways"]; calls != 1 {
+		t.Errorf("DescribeNatGateways calls = %d, want 1 shared by the EIP and NAT gateway collectors", calls)
+	}
+	for _, stat := range c.ScanStats() {
+		if (stat.Operation == "DescribeNatGateways" || stat.Operation == "DescribeSubnets") && (stat.Pages != 1 || stat.Items != 1) {
+			t.Errorf("ScanStat %+v, want 1 page and 1 item", stat)
+		}
+	}
+
+	c.ResetCache()
+	if _, err := c.FetchAllNATGateways(context.Background(), regions); err != nil {
+		t.Fatalf("FetchAllNATGateways() error = %v", err)
+	}
+	if calls := ec2Client.calls["DescribeNatGateways"]; calls != 2 {
+		t.Errorf("DescribeNatGateways calls after ResetCache = %d, want 2", calls)
+	}
+}
+
+func TestSharedErrorsNotCached(t *testing.T) {
+	c := New(&fakeClients{})
+	calls := 0
+	fetch := func() ([]string, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("throttled")
+		}
+		return []string{"nat-a"}, nil
+	}
+
+	if _, err := fetchShared(c, "us-east-1", "DescribeNatGateways", fetch); err == nil {
+		t.Fatal("fetchShared() error = nil, want the error of the first call")
+	}
+	got, err := fetchShared(c, "us-east-1", "DescribeNatGateways", fetch)
+	if err != nil || len(got) != 1 || calls != 2 {
+		t.Errorf("fetchShared() = %v, %v after %d calls, want the result of a second call", got, err, calls)
+	}
+	if _, err := fetchShared(c, "us-east-1", "DescribeNatGateways", fetch); err != nil || calls != 2 {
+		t.Errorf("fetchShared() error = %v after %d calls, want the cached result", err, calls)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+// OptInStatusNotOptedIn is the opt-in status of the regions that need to be
+// enabled in the account before they can be used.
+const OptInStatusNotOptedIn = "not-opted-in"
+
+// Reasons for skipping a region.
+const (
+	SkipReasonNotOptedIn    = "opt-in region not enabled"
+	SkipReasonUnknownRegion = "unknown region"
+)
+
+// RegionSelection limits the regions to scan. Without Include, all the enabled
+// regions are scanned, and the Exclude ones are always left out.
+type RegionSelection struct {
+	Include []string
+	Exclude []string
+}
+
+// SkippedRegion is a region that wasn't scanned even though it's available or
+// was asked for, such as opt-in regions that aren't enabled in the account.
+type SkippedRegion struct {
+	Profile string `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Account string `json:"account" yaml:"account"`
+	Region  string `json:"region" yaml:"region"`
+	Reason  string `json:"reason" yaml:"reason"`
+}
+
+// Regions are the regions of an account, split by whether they'll be scanned.
+type Regions struct {
+	// Enabled are all the regions enabled in the account, selected or not.
+	Enabled []string
+	// Selected are the enabled regions to scan.
+	Selected []string
+	// Skipped are the regions that can't be scanned. Excluded regions aren't
+	// reported as skipped.
+	Skipped []SkippedRegion
+}
+
+// SelectRegions returns the regions to scan out of all the regions of the
+// account, including the opt-in ones that aren't enabled, which are reported
+// as skipped instead of failing once scanned.
+func (c *Collector) SelectRegions(ctx context.Context, selection RegionSelection) (*Regions, error) {
+	resp, err := c.clients.EC2("").DescribeRegions(ctx, &ec2.DescribeRegionsInput{AllRegions: aws.Bool(true)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe regions: %w", err)
+	}
+
+	c.recordScan("", "DescribeRegions", 1, len(resp.Regions))
+
+	var regions Regions
+	var all []string
+	enabled := map[string]bool{}
+	for _, region := range resp.Regions {
+		name := aws.ToString(region.RegionName)
+		all = append(all, name)
+		enabled[name] = aws.ToString(region.OptInStatus) != OptInStatusNotOptedIn
+		if enabled[name] {
+			regions.Enabled = append(regions.Enabled, name)
+		}
+	}
+
+	included := map[string]bool{}
+	for _, name := range selection.Include {
+		included[name] = true
+		if _, ok := enabled[name]; !ok {
+			regions.Skipped = append(regions.Skipped, c.skippedRegion(name, SkipReasonUnknownRegion))
+		}
+	}
+	excluded := map[string]bool{}
+	for _, name := range selection.Exclude {
+		excluded[name] = true
+	}
+
+	for _, name := range all {
+		if excluded[name] || (len(included) > 0 && !included[name]) {
+			continue
+		}
+		if !enabled[name] {
+			regions.Skipped = append(regions.Skipped, c.skippedRegion(name, SkipReasonNotOptedIn))
+			continue
+		}
+		regions.Selected = append(regions.Selected, name)
+	}
+
+	return &regions, nil
+}
+
+func (c *Collector) skippedRegion(region, reason string) SkippedRegion {
+	return SkippedRegion{Profile: c.Profile, Account: c.Account, Region: region, Reason: reason}
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestSelectRegions(t *testing.T) {
+	c := New(&fakeClients{ec2: map[string]*fakeEC2{
+		"": {regions: []types.Region{
+			{RegionName: aws.String("us-east-1"), OptInStatus: aws.String("opt-in-not-required")},
+			{RegionName: aws.String("eu-west-1"), OptInStatus: aws.String("opt-in-not-required")},
+			{RegionName: aws.String("af-south-1"), OptInStatus: aws.String(OptInStatusNotOptedIn)},
+			{RegionName: aws.String("ap-east-1"), OptInStatus: aws.String("opted-in")},
+		}},
+	}})
+	c.Account = "123456789012"
+
+	tests := []struct {
+		name         string
+		selection    RegionSelection
+		wantSelected []string
+		wantSkipped  []SkippedRegion
+	}{
+		{
+			name:         "all regions",
+			wantSelected: []string{"us-east-1", "eu-west-1", "ap-east-1"},
+			wantSkipped:  []SkippedRegion{{Account: "123456789012", Region: "af-south-1", Reason: SkipReasonNotOptedIn}},
+		},
+		{
+			name:         "excluded regions",
+			selection:    RegionSelection{Exclude: []string{"eu-west-1", "af-south-1"}},
+			wantSelected: []string{"us-east-1", "ap-east-1"},
+		},
+		{
+			name:         "included regions",
+			selection:    RegionSelection{Include: []string{"eu-west-1", "af-south-1", "xx-nowhere-1"}},
+			wantSelected: []string{"eu-west-1"},
+			wantSkipped: []SkippedRegion{
+				{Account: "123456789012", Region: "xx-nowhere-1", Reason: SkipReasonUnknownRegion},
+				{Account: "123456789012", Region: "af-south-1", Reason: SkipReasonNotOptedIn},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regions, err := c.SelectRegions(context.Background(), tt.selection)
+			if err != nil {
+				t.Fatalf("SelectRegions() error = %v", err)
+			}
+			if !reflect.DeepEqual(regions.Selected, tt.wantSelected) {
+				t.Errorf("Selected = %v, want %v", regions.Selected, tt.wantSelected)
+			}
+			if !reflect.DeepEqual(regions.Skipped, tt.wantSkipped) {
+				t.Errorf("Skipped = %+v, want %+v", regions.Skipped, tt.wantSkipped)
+			}
+			if len(regions.Enabled) != 3 {
+				t.Errorf("Enabled = %v, want the 3 enabled regions", regions.Enabled)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+type SubnetInfo struct {
+	Profile             string `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Account             string `json:"account" yaml:"account"`
+	Region              string `json:"region" yaml:"region"`
+	VPCID               string `json:"vpc_id" yaml:"vpc_id"`
+	SubnetID            string `json:"subnet_id" yaml:"subnet_id"`
+	AvailabilityZone    string `json:"availability_zone" yaml:"availability_zone"`
+	MapPublicIPOnLaunch bool   `json:"map_public_ip_on_launch" yaml:"map_public_ip_on_launch"`
+	// IPv6 is set for the subnets with an associated IPv6 CIDR block
+	IPv6 bool `json:"ipv6" yaml:"ipv6"`
+}
+
+func hasIPv6CIDR(subnet types.Subnet) bool {
+	for _, association := range subnet.Ipv6CidrBlockAssociationSet {
+		if association.Ipv6CidrBlockState != nil && association.Ipv6CidrBlockState.State == types.SubnetCidrBlockStateCodeAssociated {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchSubnetsInRegion returns the subnets of a region, fetched once for the
+// load balancer, NAT gateway and subnet collectors.
+func (c *Collector) fetchSubnetsInRegion(ctx context.Context, regionName string) ([]SubnetInfo, error) {
+	return fetchShared(c, regionName, "DescribeSubnets", func() ([]SubnetInfo, error) {
+		return c.describeSubnets(ctx, regionName)
+	})
+}
+
+func (c *Collector) describeSubnets(ctx context.Context, regionName string) ([]SubnetInfo, error) {
+	var subnets []SubnetInfo
+	pages := 0
+
+	paginator := ec2.NewDescribeSubnetsPaginator(c.clients.EC2(regionName), &ec2.DescribeSubnetsInput{})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe subnets: %w", err)
+		}
+		pages++
+
+		for _, subnet := range resp.Subnets {
+			subnets = append(subnets, SubnetInfo{
+				Profile:             c.Profile,
+				Account:             c.Account,
+				Region:              regionName,
+				VPCID:               aws.ToString(subnet.VpcId),
+				SubnetID:            aws.ToString(subnet.SubnetId),
+				AvailabilityZone:    aws.ToString(subnet.AvailabilityZone),
+				MapPublicIPOnLaunch: aws.ToBool(subnet.MapPublicIpOnLaunch),
+				IPv6:                hasIPv6CIDR(subnet),
+			})
+		}
+	}
+
+	c.recordScan(regionName, "DescribeSubnets", pages, len(subnets))
+	return subnets, nil
+}
+
+// FetchAllSubnets returns the subnets from all the given regions, in the
+// order of the regions. It only reads the subnets and never changes them.
+func (c *Collector) FetchAllSubnets(ctx context.Context, regions []string) ([]SubnetInfo, error) {
+	var subnets []SubnetInfo
+	var errs []error
+	for _, region := range regions {
+		regionSubnets, err := c.fetchSubnetsInRegion(ctx, region)
+		if err != nil {
+			errs = append(errs, &RegionError{Account: c.Account, Region: region, Err: err})
+			continue
+		}
+		subnets = append(subnets, regionSubnets...)
+	}
+	return subnets, errors.Join(errs...)
+}
+
+// SetMapPublicIPOnLaunch changes whether the subnet auto-assigns public IPv4
+// addresses to the instances launched in it.
+func (c *Collector) SetMapPublicIPOnLaunch(ctx context.Context, subnet SubnetInfo, value bool) error {
+	_, err := c.clients.EC2(subnet.Region).ModifySubnetAttribute(ctx, &ec2.ModifySubnetAttributeInput{
+		SubnetId: aws.String(subnet.SubnetID),
+		MapPublicIpOnLaunch: &types.AttributeBooleanValue{
+			Value: aws.Bool(value),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to toggle Auto-Attach IP for subnet %s: %w", subnet.SubnetID, err)
+	}
+	c.forgetShared(subnet.Region, "DescribeSubnets")
+	return nil
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestFetchAllSubnetsIsReadOnly(t *testing.T) {
+	fake := &fakeEC2{subnets: []types.Subnet{
+		{SubnetId: aws.String("subnet-1"), VpcId: aws.String("vpc-1"), MapPublicIpOnLaunch: aws.Bool(true)},
+		{SubnetId: aws.String("subnet-2"), VpcId: aws.String("vpc-1"), MapPublicIpOnLaunch: aws.Bool(false)},
+	}}
+	c := New(&fakeClients{ec2: map[string]*fakeEC2{"us-east-1": fake}})
+
+	subnets, err := c.FetchAllSubnets(context.Background(), []string{"us-east-1"})
+	if err != nil {
+		t.Fatalf("FetchAllSubnets() error = %v", err)
+	}
+
+	want := []SubnetInfo{
+		{Region: "us-east-1", VPCID: "vpc-1", SubnetID: "subnet-1", MapPublicIPOnLaunch: true},
+		{Region: "us-east-1", VPCID: "vpc-1", SubnetID: "subnet-2", MapPublicIPOnLaunch: false},
+	}
+	if len(subnets) != len(want) || subnets[0] != want[0] || subnets[1] != want[1] {
+		t.Errorf("FetchAllSubnets() = %+v, want %+v", subnets, want)
+	}
+	if len(fake.modifiedSubnets) != 0 {
+		t.Errorf("FetchAllSubnets() modified %d subnets, want none", len(fake.modifiedSubnets))
+	}
+}
+
+func TestSetMapPublicIPOnLaunch(t *testing.T) {
+	fake := &fakeEC2{}
+	c := New(&fakeClients{ec2: map[string]*fakeEC2{"eu-west-1": fake}})
+
+	subnet := SubnetInfo{Region: "eu-west-1", SubnetID: "subnet-1", MapPublicIPOnLaunch: true}
+	if err := c.SetMapPublicIPOnLaunch(context.Background(), subnet, false); err != nil {
+		t.Fatalf("SetMapPublicIPOnLaunch() error = %v", err)
+	}
+
+	if len(fake.modifiedSubnets) != 1 {
+		t.Fatalf("SetMapPublicIPOnLaunch() modified %d subnets, want 1", len(fake.modifiedSubnets))
+	}
+	input := fake.modifiedSubnets[0]
+	if aws.ToString(input.SubnetId) != "subnet-1" || aws.ToBool(input.MapPublicIpOnLaunch.Value) {
+		t.Errorf("ModifySubnetAttribute() called with subnet %s and value %v, want subnet-1 and false",
+			aws.ToString(input.SubnetId), aws.ToBool(input.MapPublicIpOnLaunch.Value))
+	}
+}
@@ -14,9 +14,30 @@
  * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
  */
 
-package main
+package collector
 
-import "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/pricing"
+)
+
+// The public IPv4 pool of the addresses owned by Amazon, the other pools are
+// brought by the customer (BYOIP)
+const PublicIPv4PoolAmazon = "amazon"
+
+// ipOwner classifies an address as owned by Amazon, brought by the customer or
+// customer-owned on an Outpost, from its public IPv4 pool and customer-owned
+// IP.
+func ipOwner(publicIPv4Pool, customerOwnedIP *string) string {
+	switch pool := aws.ToString(publicIPv4Pool); {
+	case aws.ToString(customerOwnedIP) != "":
+		return pricing.IPOwnerCustomerOwned
+	case pool != "" && pool != PublicIPv4PoolAmazon:
+		return pricing.IPOwnerBYOIP
+	}
+	return pricing.IPOwnerAmazon
+}
 
 func getTagValue(tags []types.Tag, key string) string {
 	for _, tag := range tags {
@@ -37,3 +58,15 @@ func getNameTagValue(tags []types.Tag) string {
 	}
 	return nameTag
 }
+
+// getTags returns the tags as a map, or nil when there are none
+func getTags(tags []types.Tag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return m
+}
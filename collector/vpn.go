@@ -0,0 +1,342 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// VPNConnectionInfo is a Site-to-Site VPN connection with the outside IPs of
+// the AWS side of its tunnels. They're only public for the VPN connections
+// over the internet, while the private IP VPNs over Direct Connect use
+// private IPv4 addresses, which aren't charged.
+type VPNConnectionInfo struct {
+	Profile              string   `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Account              string   `json:"account" yaml:"account"`
+	Region               string   `json:"region" yaml:"region"`
+	VPNConnectionID      string   `json:"vpn_connection_id" yaml:"vpn_connection_id"`
+	NameTag              string   `json:"name_tag" yaml:"name_tag"`
+	State                string   `json:"state" yaml:"state"`
+	Gateway              string   `json:"gateway" yaml:"gateway"`
+	CustomerGatewayID    string   `json:"customer_gateway_id" yaml:"customer_gateway_id"`
+	OutsideIPAddressType string   `json:"outside_ip_address_type" yaml:"outside_ip_address_type"`
+	Accelerated          bool     `json:"accelerated" yaml:"accelerated"`
+	TunnelOutsideIPs     []string `json:"tunnel_outside_ips" yaml:"tunnel_outside_ips"`
+	IPCount              int      `json:"ip_count" yaml:"ip_count"`
+	PublicIPs            []string `json:"public_ips" yaml:"public_ips"`
+	// UnchargedIPCount are the public IPs that aren't owned by Amazon, such
+	// as BYOIP EIPs, which are left out of the costs
+	UnchargedIPCount int     `json:"uncharged_ip_count" yaml:"uncharged_ip_count"`
+	Cost             float64 `json:"cost" yaml:"cost"`
+}
+
+// ClientVPNEndpointInfo is a Client VPN endpoint with the public IPs of the
+// ENIs it created in its associated subnets.
+type ClientVPNEndpointInfo struct {
+	Profile             string   `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Account             string   `json:"account" yaml:"account"`
+	Region              string   `json:"region" yaml:"region"`
+	ClientVPNEndpointID string   `json:"client_vpn_endpoint_id" yaml:"client_vpn_endpoint_id"`
+	NameTag             string   `json:"name_tag" yaml:"name_tag"`
+	Status              string   `json:"status" yaml:"status"`
+	DNSName             string   `json:"dns_name" yaml:"dns_name"`
+	VPCID               string   `json:"vpc_id" yaml:"vpc_id"`
+	SubnetIDs           []string `json:"subnet_ids" yaml:"subnet_ids"`
+	ENIIDs              []string `json:"eni_ids" yaml:"eni_ids"`
+	IPCount             int      `json:"ip_count" yaml:"ip_count"`
+	PublicIPs           []string `json:"public_ips" yaml:"public_ips"`
+	// UnchargedIPCount are the public IPs that aren't owned by Amazon, such
+	// as BYOIP EIPs, which are left out of the costs
+	UnchargedIPCount int     `json:"uncharged_ip_count" yaml:"uncharged_ip_count"`
+	Cost             float64 `json:"cost" yaml:"cost"`
+}
+
+const (
+	OutsideIPAddressTypePublic  = "PublicIpv4"
+	OutsideIPAddressTypePrivate = "PrivateIpv4"
+
+	GatewayTypeTransitGateway = "Transit Gateway"
+	GatewayTypeVPNGateway     = "VPN Gateway"
+)
+
+// FetchAllVPNConnections returns the Site-to-Site VPN connections that
+// aren't deleted from all the given regions. When the EIPs of a region can't
+// be described, its BYOIP addresses are charged like the others, along with
+// the error.
+func (c *Collector) FetchAllVPNConnections(ctx context.Context, regions []string) ([]VPNConnectionInfo, error) {
+	var allVPNConnections []VPNConnectionInfo
+	var errs []error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, region := range regions {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+
+			vpnConnections, err := c.fetchRegionVPNConnections(ctx, region)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, &RegionError{Account: c.Account, Region: region, Err: err})
+			}
+			allVPNConnections = append(allVPNConnections, vpnConnections...)
+		}(region)
+	}
+	wg.Wait()
+
+	return allVPNConnections, errors.Join(errs...)
+}
+
+func (c *Collector) fetchRegionVPNConnections(ctx context.Context, region string) ([]VPNConnectionInfo, error) {
+	// DescribeVpnConnections isn't paginated, it always returns all the VPN
+	// connections
+	resp, err := c.clients.EC2(region).DescribeVpnConnections(ctx, &ec2.DescribeVpnConnectionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe VPN connections: %w", err)
+	}
+	c.recordScan(region, "DescribeVpnConnections", 1, len(resp.VpnConnections))
+
+	// Without the EIPs, the BYOIP addresses are charged like the others
+	var owners ipOwners
+	if len(resp.VpnConnections) > 0 {
+		owners, err = c.fetchIPOwners(ctx, region)
+	}
+
+	var infos []VPNConnectionInfo
+	for _, vpnConnection := range resp.VpnConnections {
+		if vpnConnection.State == types.VpnStateDeleted {
+			continue
+		}
+
+		info := VPNConnectionInfo{
+			Profile:              c.Profile,
+			Account:              c.Account,
+			Region:               region,
+			VPNConnectionID:      aws.ToString(vpnConnection.VpnConnectionId),
+			NameTag:              getNameTagValue(vpnConnection.Tags),
+			State:                string(vpnConnection.State),
+			Gateway:              vpnGateway(vpnConnection),
+			CustomerGatewayID:    aws.ToString(vpnConnection.CustomerGatewayId),
+			OutsideIPAddressType: OutsideIPAddressTypePublic,
+			TunnelOutsideIPs:     tunnelOutsideIPs(vpnConnection),
+		}
+		if options := vpnConnection.Options; options != nil {
+			if options.OutsideIpAddressType != nil {
+				info.OutsideIPAddressType = aws.ToString(options.OutsideIpAddressType)
+			}
+			info.Accelerated = aws.ToBool(options.EnableAcceleration)
+		}
+		if info.OutsideIPAddressType != OutsideIPAddressTypePrivate {
+			info.PublicIPs = info.TunnelOutsideIPs
+		}
+		info.IPCount = len(info.PublicIPs)
+		info.UnchargedIPCount = owners.uncharged(info.PublicIPs)
+		info.Cost = c.Pricing.MonthlyCost(region, info.IPCount-info.UnchargedIPCount)
+
+		infos = append(infos, info)
+	}
+	return infos, err
+}
+
+// vpnGateway returns the gateway on the AWS side of a VPN connection, as
+// "<type>: <ID>", or an empty string for those attached to Cloud WAN.
+func vpnGateway(vpnConnection types.VpnConnection) string {
+	switch {
+	case vpnConnection.TransitGatewayId != nil:
+		return GatewayTypeTransitGateway + ": " + aws.ToString(vpnConnection.TransitGatewayId)
+	case vpnConnection.VpnGatewayId != nil:
+		return GatewayTypeVPNGateway + ": " + aws.ToString(vpnConnection.VpnGatewayId)
+	}
+	return ""
+}
+
+// tunnelOutsideIPs returns the outside IPs of the AWS side of the tunnels of
+// a VPN connection, from its tunnel options or else from the telemetry of its
+// tunnels.
+func tunnelOutsideIPs(vpnConnection types.VpnConnection) []string {
+	var ips []string
+	if vpnConnection.Options != nil {
+		for _, tunnel := range vpnConnection.Options.TunnelOptions {
+			if ip := aws.ToString(tunnel.OutsideIpAddress); ip != "" {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	if len(ips) > 0 {
+		return ips
+	}
+	for _, telemetry := range vpnConnection.VgwTelemetry {
+		if ip := aws.ToString(telemetry.OutsideIpAddress); ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// FetchAllClientVPNEndpoints returns the Client VPN endpoints that aren't
+// deleted from all the given regions, with the public IPs of their ENIs. When
+// the EIPs of a region can't be described, its BYOIP addresses are charged
+// like the others, along with the error.
+func (c *Collector) FetchAllClientVPNEndpoints(ctx context.Context, regions []string) ([]ClientVPNEndpointInfo, error) {
+	var allEndpoints []ClientVPNEndpointInfo
+	var errs []error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, region := range regions {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+
+			endpoints, err := c.fetchRegionClientVPNEndpoints(ctx, region)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, &RegionError{Account: c.Account, Region: region, Err: err})
+			}
+			allEndpoints = append(allEndpoints, endpoints...)
+		}(region)
+	}
+	wg.Wait()
+
+	return allEndpoints, errors.Join(errs...)
+}
+
+func (c *Collector) fetchRegionClientVPNEndpoints(ctx context.Context, region string) ([]ClientVPNEndpointInfo, error) {
+	var endpoints []types.ClientVpnEndpoint
+	pages, items := 0, 0
+
+	paginator := ec2.NewDescribeClientVpnEndpointsPaginator(c.clients.EC2(region), &ec2.DescribeClientVpnEndpointsInput{})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe Client VPN endpoints: %w", err)
+		}
+		pages++
+		items += len(resp.ClientVpnEndpoints)
+
+		for _, endpoint := range resp.ClientVpnEndpoints {
+			if endpoint.Status == nil || endpoint.Status.Code != types.ClientVpnEndpointStatusCodeDeleted {
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+	}
+	c.recordScan(region, "DescribeClientVpnEndpoints", pages, items)
+	if len(endpoints) == 0 {
+		return nil, nil
+	}
+
+	var infos []ClientVPNEndpointInfo
+	endpointIndexes := map[string]int{}
+	for _, endpoint := range endpoints {
+		info := ClientVPNEndpointInfo{
+			Profile:             c.Profile,
+			Account:             c.Account,
+			Region:              region,
+			ClientVPNEndpointID: aws.ToString(endpoint.ClientVpnEndpointId),
+			NameTag:             getNameTagValue(endpoint.Tags),
+			DNSName:             aws.ToString(endpoint.DnsName),
+			VPCID:               aws.ToString(endpoint.VpcId),
+		}
+		if endpoint.Status != nil {
+			info.Status = string(endpoint.Status.Code)
+		}
+		endpointIndexes[info.ClientVPNEndpointID] = len(infos)
+		infos = append(infos, info)
+	}
+
+	enis, err := c.fetchClientVPNNetworkInterfaces(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	for _, eni := range enis {
+		i, ok := endpointIndexes[clientVPNEndpointID(eni)]
+		if !ok {
+			continue
+		}
+		info := &infos[i]
+		info.ENIIDs = append(info.ENIIDs, aws.ToString(eni.NetworkInterfaceId))
+		if subnetID := aws.ToString(eni.SubnetId); subnetID != "" && !slices.Contains(info.SubnetIDs, subnetID) {
+			info.SubnetIDs = append(info.SubnetIDs, subnetID)
+		}
+		for _, ip := range eniPublicIPs(eni) {
+			info.PublicIPs = append(info.PublicIPs, ip.publicIP)
+		}
+	}
+
+	// Without the EIPs, the BYOIP addresses are charged like the others
+	owners, err := c.fetchIPOwners(ctx, region)
+	for i := range infos {
+		info := &infos[i]
+		info.IPCount = len(info.PublicIPs)
+		info.UnchargedIPCount = owners.uncharged(info.PublicIPs)
+		info.Cost = c.Pricing.MonthlyCost(region, info.IPCount-info.UnchargedIPCount)
+	}
+	return infos, err
+}
+
+// fetchClientVPNNetworkInterfaces returns the requester managed ENIs created
+// by the Client VPN endpoints in their associated subnets.
+func (c *Collector) fetchClientVPNNetworkInterfaces(ctx context.Context, region string) ([]types.NetworkInterface, error) {
+	var enis []types.NetworkInterface
+	pages, items := 0, 0
+
+	paginator := ec2.NewDescribeNetworkInterfacesPaginator(c.clients.EC2(region), &ec2.DescribeNetworkInterfacesInput{
+		Filters: []types.Filter{
+			{Name: aws.String("description"), Values: []string{"*cvpn-endpoint-*"}},
+			{Name: aws.String("requester-managed"), Values: []string{"true"}},
+		},
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe the Client VPN ENIs: %w", err)
+		}
+		pages++
+		items += len(resp.NetworkInterfaces)
+		enis = append(enis, resp.NetworkInterfaces...)
+	}
+
+	c.recordScan(region, "DescribeNetworkInterfaces (Client VPN)", pages, items)
+	return enis, nil
+}
+
+// clientVPNEndpointIDPattern matches the ID of the Client VPN endpoint in the
+// description of the ENIs it creates.
+var clientVPNEndpointIDPattern = regexp.MustCompile(`cvpn-endpoint-[0-9a-f]+`)
+
+// clientVPNEndpointID returns the ID of the Client VPN endpoint owning an
+// ENI, which the endpoint doesn't list but writes in the ENI description, or
+// "" when it isn't a requester managed ENI of a Client VPN endpoint.
+func clientVPNEndpointID(eni types.NetworkInterface) string {
+	if !aws.ToBool(eni.RequesterManaged) {
+		return ""
+	}
+	return clientVPNEndpointIDPattern.FindString(aws.ToString(eni.Description))
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestFetchAllVPNConnections(t *testing.T) {
+	c := New(&fakeClients{ec2: map[string]*fakeEC2{"us-east-1": {
+		vpnConnections: []types.VpnConnection{
+			{
+				VpnConnectionId:   aws.String("vpn-public"),
+				State:             types.VpnStateAvailable,
+				VpnGatewayId:      aws.String("vgw-1"),
+				CustomerGatewayId: aws.String("cgw-1"),
+				Tags:              []types.Tag{{Key: aws.String("Name"), Value: aws.String("office")}},
+				Options: &types.VpnConnectionOptions{TunnelOptions: []types.TunnelOption{
+					{OutsideIpAddress: aws.String("3.3.3.1")},
+					{OutsideIpAddress: aws.String("3.3.3.2")},
+				}},
+			},
+			{
+				VpnConnectionId:   aws.String("vpn-telemetry"),
+				State:             types.VpnStateAvailable,
+				TransitGatewayId:  aws.String("tgw-1"),
+				CustomerGatewayId: aws.String("cgw-2"),
+				VgwTelemetry:      []types.VgwTelemetry{{OutsideIpAddress: aws.String("4.4.4.1")}},
+			},
+			{
+				VpnConnectionId:   aws.String("vpn-private"),
+				State:             types.VpnStateAvailable,
+				TransitGatewayId:  aws.String("tgw-1"),
+				CustomerGatewayId: aws.String("cgw-3"),
+				Options: &types.VpnConnectionOptions{
+					OutsideIpAddressType: aws.String(OutsideIPAddressTypePrivate),
+					TunnelOptions:        []types.TunnelOption{{OutsideIpAddress: aws.String("10.0.0.1")}},
+				},
+			},
+			{VpnConnectionId: aws.String("vpn-deleted"), State: types.VpnStateDeleted},
+		},
+	}}})
+
+	vpnConnections, err := c.FetchAllVPNConnections(context.Background(), []string{"us-east-1"})
+	if err != nil {
+		t.Fatalf("FetchAllVPNConnections() error = %v", err)
+	}
+
+	want := []VPNConnectionInfo{
+		{Region: "us-east-1", VPNConnectionID: "vpn-public", NameTag: "office", State: "available", Gateway: "VPN Gateway: vgw-1",
+			CustomerGatewayID: "cgw-1", OutsideIPAddressType: "PublicIpv4", TunnelOutsideIPs: []string{"3.3.3.1", "3.3.3.2"},
+			IPCount: 2, PublicIPs: []string{"3.3.3.1", "3.3.3.2"}, Cost: 7.30},
+		{Region: "us-east-1", VPNConnectionID: "vpn-telemetry", State: "available", Gateway: "Transit Gateway: tgw-1",
+			CustomerGatewayID: "cgw-2", OutsideIPAddressType: "PublicIpv4", TunnelOutsideIPs: []string{"4.4.4.1"},
+			IPCount: 1, PublicIPs: []string{"4.4.4.1"}, Cost: 3.65},
+		{Region: "us-east-1", VPNConnectionID: "vpn-private", State: "available", Gateway: "Transit Gateway: tgw-1",
+			CustomerGatewayID: "cgw-3", OutsideIPAddressType: "PrivateIpv4", TunnelOutsideIPs: []string{"10.0.0.1"}},
+	}
+	if !reflect.DeepEqual(vpnConnections, want) {
+		t.Errorf("FetchAllVPNConnections() = %+v, want %+v", vpnConnections, want)
+	}
+}
+
+func TestFetchAllClientVPNEndpoints(t *testing.T) {
+	clientVPNENI := func(id, subnet, endpointID, publicIP string) types.NetworkInterface {
+		return types.NetworkInterface{
+			NetworkInterfaceId: aws.String(id),
+			SubnetId:           aws.String(subnet),
+			RequesterManaged:   aws.Bool(true),
+			Description:        aws.String("ClientVPN Endpoint resource " + endpointID),
+			Association:        &types.NetworkInterfaceAssociation{PublicIp: aws.String(publicIP)},
+		}
+	}
+	notRequesterManaged := clientVPNENI("eni-user", "subnet-a", "cvpn-endpoint-1", "5.5.5.9")
+	notRequesterManaged.RequesterManaged = aws.Bool(false)
+
+	c := New(&fakeClients{ec2: map[string]*fakeEC2{"us-east-1": {
+		pageSize: 1,
+		clientVPNs: []types.ClientVpnEndpoint{
+			{
+				ClientVpnEndpointId: aws.String("cvpn-endpoint-1"),
+				Status:              &types.ClientVpnEndpointStatus{Code: types.ClientVpnEndpointStatusCodeAvailable},
+				DnsName:             aws.String("*.cvpn-endpoint-1.prod.clientvpn.us-east-1.amazonaws.com"),
+				VpcId:               aws.String("vpc-1"),
+			},
+			{
+				ClientVpnEndpointId: aws.String("cvpn-endpoint-2"),
+				Status:              &types.ClientVpnEndpointStatus{Code: types.ClientVpnEndpointStatusCodePendingAssociate},
+				VpcId:               aws.String("vpc-1"),
+			},
+			{
+				ClientVpnEndpointId: aws.String("cvpn-endpoint-gone"),
+				Status:              &types.ClientVpnEndpointStatus{Code: types.ClientVpnEndpointStatusCodeDeleted},
+			},
+		},
+		networkInterfaces: []types.NetworkInterface{
+			clientVPNENI("eni-a", "subnet-a", "cvpn-endpoint-1", "5.5.5.1"),
+			clientVPNENI("eni-b", "subnet-b", "cvpn-endpoint-1", "5.5.5.2"),
+			// Endpoints sharing subnets get only their own ENIs
+			clientVPNENI("eni-c", "subnet-a", "cvpn-endpoint-2", "5.5.5.3"),
+			clientVPNENI("eni-gone", "subnet-a", "cvpn-endpoint-gone", "5.5.5.4"),
+			notRequesterManaged,
+		},
+	}}})
+
+	endpoints, err := c.FetchAllClientVPNEndpoints(context.Background(), []string{"us-east-1"})
+	if err != nil {
+		t.Fatalf("FetchAllClientVPNEndpoints() error = %v", err)
+	}
+
+	want := []ClientVPNEndpointInfo{{
+		Region: "us-east-1", ClientVPNEndpointID: "cvpn-endpoint-1", Status: "available",
+		DNSName: "*.cvpn-endpoint-1.prod.clientvpn.us-east-1.amazonaws.com", VPCID: "vpc-1",
+		SubnetIDs: []string{"subnet-a", "subnet-b"}, ENIIDs: []string{"eni-a", "eni-b"},
+		IPCount: 2, PublicIPs: []string{"5.5.5.1", "5.5.5.2"}, Cost: 7.30,
+	}, {
+		Region: "us-east-1", ClientVPNEndpointID: "cvpn-endpoint-2", Status: "pending-associate", VPCID: "vpc-1",
+		SubnetIDs: []string{"subnet-a"}, ENIIDs: []string{"eni-c"}, IPCount: 1, PublicIPs: []string{"5.5.5.3"}, Cost: 3.65,
+	}}
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("FetchAllClientVPNEndpoints() = %+v, want %+v", endpoints, want)
+	}
+}
+
+func TestFetchAllVPNConnectionsError(t *testing.T) {
+	c := New(&fakeClients{ec2: map[string]*fakeEC2{
+		"us-east-1": {err: errors.New("throttled")},
+	}})
+
+	_, err := c.FetchAllVPNConnections(context.Background(), []string{"us-east-1"})
+
+	var regionErr *RegionError
+	if !errors.As(err, &regionErr) || regionErr.Region != "us-east-1" {
+		t.Errorf("FetchAllVPNConnections() error = %v, want a *RegionError for us-east-1", err)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/leanercloud/aws-ipv4-cost-viewer/collector"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/report"
+)
+
+const DefaultXLSXOutputFile = "ipv4-costs.xlsx"
+
+// exportView collects the data without starting the UI and writes it in the
+// given format. JSON and YAML are written as a single document to outputFile,
+// or to stdout if it's empty, CSV is written as one file per resource type in
+// outputDir and XLSX as a workbook with one sheet per resource type.
+func exportView(collectors []*collector.Collector, selection collector.RegionSelection, format, outputFile, outputDir string) error {
+	switch format {
+	case report.FormatJSON, report.FormatCSV, report.FormatYAML, report.FormatXLSX:
+	default:
+		return fmt.Errorf("unsupported output format %q, expected one of json, csv, yaml or xlsx", format)
+	}
+
+	r, err := report.CollectAll(context.Background(), collectors, selection)
+	if err != nil {
+		if r == nil {
+			return err
+		}
+		log.Printf("Error fetching some of the data, exporting what was collected: %v", err)
+	}
+	for _, warning := range r.Warnings {
+		log.Printf("Warning: %s", warning)
+	}
+	for _, skipped := range r.SkippedRegions {
+		log.Printf("Skipped region %s in account %s: %s", skipped.Region, skipped.Account, skipped.Reason)
+	}
+
+	switch format {
+	case report.FormatCSV:
+		if err := r.WriteCSVFiles(outputDir); err != nil {
+			return err
+		}
+		log.Printf("Wrote the CSV files to %s", outputDir)
+		return nil
+	case report.FormatXLSX:
+		if outputFile == "" {
+			outputFile = DefaultXLSXOutputFile
+		}
+		if err := r.WriteXLSXFile(outputFile); err != nil {
+			return err
+		}
+		log.Printf("Wrote the workbook to %s", outputFile)
+		return nil
+	}
+
+	w := os.Stdout
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if format == report.FormatJSON {
+		return r.WriteJSON(w)
+	}
+	return r.WriteYAML(w)
+}
@@ -18,12 +18,15 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
 
-	"github.com/aws/aws-sdk-go-v2/service/ec2"
-	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/accounts"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/collector"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/pricing"
 )
 
 var debug *log.Logger
@@ -34,23 +37,109 @@ func init() {
 	debugEnv := os.Getenv("DEBUG")
 	if debugEnv == "true" {
 		debug = log.New(os.Stdout, "[DEBUG] ", log.LstdFlags|log.Lshortfile)
+		collector.SetDebugLogger(debug)
 	} else {
 		debug = log.New(io.Discard, "", 0) // No-op logger
 	}
 }
 
 func main() {
-	if len(os.Args) > 1 && os.Args[1] == "--subnets" {
-		handleSubnets()
-	} else {
-		ipCostsView()
+	subnets := flag.Bool("subnets", false, "Show the VPC subnets and their Auto-Attach IP setting")
+	dryRun := flag.Bool("dry-run", false, "With --subnets, only print the subnets that would be changed")
+	output := flag.String("output", "", "Write the data as json, csv, yaml or xlsx instead of starting the UI")
+	outputFile := flag.String("output-file", "", "File to write the json or yaml output to, defaults to stdout, or the xlsx workbook to")
+	outputDir := flag.String("output-dir", ".", "Directory to write the csv files to")
+	orgAccounts := flag.Bool("org-accounts", false, "Scan all the active accounts of the AWS Organization")
+	accountIDs := flag.String("accounts", "", "Comma separated list of account IDs to scan")
+	roleName := flag.String("role-name", accounts.DefaultRoleName, "Role assumed in the accounts other than the current one")
+	var profiles stringList
+	flag.Var(&profiles, "profile", "Shared config profile to scan, can be repeated to scan several of them")
+	allProfiles := flag.Bool("all-profiles", false, "Scan all the profiles from the shared config and credentials files")
+	var regions, excludeRegions stringList
+	flag.Var(&regions, "regions", "Comma separated list of regions to scan, instead of all the enabled ones")
+	flag.Var(&excludeRegions, "exclude-regions", "Comma separated list of regions not to scan")
+	hourlyRate := flag.Float64("hourly-rate", pricing.DefaultHourlyRate, "Hourly price of a public IPv4 address")
+	regionRates := flag.String("region-rates", "", "Comma separated region=rate list of hourly prices overriding --hourly-rate")
+	currency := flag.String("currency", pricing.DefaultCurrency, "Currency of the hourly prices")
+	natHourlyRate := flag.Float64("nat-hourly-rate", pricing.DefaultNATGatewayHourlyRate, "Hourly price of a NAT gateway, used for the consolidation savings")
+	freeTier := flag.Bool("free-tier", false, "Deduct the 750 monthly hours of the AWS Free Tier from each account")
+	lbDNSFallback := flag.Bool("lb-dns-fallback", false, "Resolve the DNS names of the internet facing load balancers without public IPs found on their ENIs")
+	lookback := flag.String("lookback", collector.FormatLookback(collector.DefaultLookback), "Window of the load balancer and NAT gateway metrics, such as 30d or 12h")
+	flag.Parse()
+
+	if *dryRun && !*subnets {
+		log.Fatal("--dry-run can only be used with --subnets")
 	}
-}
 
-func fetchRegions(client *ec2.Client) ([]types.Region, error) {
-	regions, err := client.DescribeRegions(context.TODO(), &ec2.DescribeRegionsInput{})
+	lookbackWindow, err := collector.ParseLookback(*lookback)
 	if err != nil {
-		return nil, err
+		log.Fatalf("Invalid lookback: %v", err)
+	}
+
+	model, err := pricingModel(*hourlyRate, *natHourlyRate, *regionRates, *currency, *freeTier)
+	if err != nil {
+		log.Fatalf("Invalid pricing: %v", err)
+	}
+
+	selection := collector.RegionSelection{Include: regions, Exclude: excludeRegions}
+
+	collectors, err := loadCollectors(context.Background(), accountOptions{
+		profiles:    profiles,
+		allProfiles: *allProfiles,
+		orgAccounts: *orgAccounts,
+		accountIDs:  *accountIDs,
+		roleName:    *roleName,
+	})
+	if err != nil {
+		log.Fatalf("Failed to load the accounts: %v", err)
+	}
+
+	if *subnets {
+		handleSubnets(collectors, *dryRun, selection)
+		return
+	}
+
+	for _, c := range collectors {
+		c.Pricing = model
+		c.LoadBalancerDNSFallback = *lbDNSFallback
+		c.Lookback = lookbackWindow
+	}
+
+	if *output != "" {
+		if err := exportView(collectors, selection, *output, *outputFile, *outputDir); err != nil {
+			log.Fatalf("Failed to export data: %v", err)
+		}
+		return
+	}
+
+	if err := ipCostsView(collectors, selection); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// pricingModel returns the pricing model configured from the command line.
+func pricingModel(hourlyRate, natHourlyRate float64, regionRates, currency string, freeTier bool) (*pricing.Model, error) {
+	if hourlyRate < 0 {
+		return nil, fmt.Errorf("negative hourly rate %v", hourlyRate)
+	}
+	if natHourlyRate < 0 {
+		return nil, fmt.Errorf("negative NAT gateway hourly rate %v", natHourlyRate)
+	}
+
+	model := pricing.Default()
+	model.HourlyRate = hourlyRate
+	model.NATGatewayHourlyRate = natHourlyRate
+	model.Currency = currency
+	if freeTier {
+		model.FreeTierHours = pricing.FreeTierHours
+	}
+
+	if regionRates != "" {
+		rates, err := pricing.ParseRegionRates(regionRates)
+		if err != nil {
+			return nil, err
+		}
+		model.RegionHourlyRates = rates
 	}
-	return regions.Regions, nil
+	return model, nil
 }
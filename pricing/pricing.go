@@ -0,0 +1,202 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+// Package pricing contains the cost model used for public IPv4 addresses.
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultHourlyRate is the price AWS charges for each public IPv4 address
+	DefaultHourlyRate = 0.005
+	// DefaultCurrency is the currency of DefaultHourlyRate
+	DefaultCurrency = "USD"
+	// HoursInMonth is the number of hours AWS uses for monthly estimates
+	HoursInMonth = 730
+	// MonthsInYear is used for the annual estimates
+	MonthsInYear = 12
+	// FreeTierHours are the monthly public IPv4 hours included in the AWS Free
+	// Tier of each account, during its first 12 months
+	FreeTierHours = 750
+	// DefaultNATGatewayHourlyRate is the price AWS charges for each NAT
+	// gateway, on top of its data processing and public IPs
+	DefaultNATGatewayHourlyRate = 0.045
+)
+
+// Owners of the public IPv4 addresses. Only those owned by Amazon are charged,
+// not those brought by the customer (BYOIP) or the customer-owned ones of the
+// Outposts.
+const (
+	IPOwnerAmazon        = "amazon"
+	IPOwnerBYOIP         = "byoip"
+	IPOwnerCustomerOwned = "customer-owned"
+)
+
+// Charged reports whether the public IPv4 addresses of the owner are charged,
+// which is assumed when the owner isn't known.
+func Charged(ipOwner string) bool {
+	return ipOwner == "" || ipOwner == IPOwnerAmazon
+}
+
+// Model computes the costs of public IPv4 addresses. Its zero value is not
+// usable, start from Default and adjust it instead.
+type Model struct {
+	// HourlyRate is the price of a public IPv4 address for one hour
+	HourlyRate float64
+	// RegionHourlyRates override HourlyRate in some of the regions
+	RegionHourlyRates map[string]float64
+	// Currency of the rates, only used for display
+	Currency string
+	// FreeTierHours are the monthly hours covered by the Free Tier of each
+	// account, 0 when the accounts aren't eligible for it
+	FreeTierHours float64
+	// NATGatewayHourlyRate is the price of a NAT gateway for one hour, in the
+	// same currency
+	NATGatewayHourlyRate float64
+}
+
+// Default returns the model with the AWS list price and no Free Tier.
+func Default() *Model {
+	return &Model{
+		HourlyRate:           DefaultHourlyRate,
+		Currency:             DefaultCurrency,
+		NATGatewayHourlyRate: DefaultNATGatewayHourlyRate,
+	}
+}
+
+// Cost is the cost of some public IPv4 addresses over different periods.
+type Cost struct {
+	Hourly  float64 `json:"hourly" yaml:"hourly"`
+	Monthly float64 `json:"monthly" yaml:"monthly"`
+	Annual  float64 `json:"annual" yaml:"annual"`
+}
+
+// FromMonthly returns the hourly and annual costs matching a monthly cost.
+func FromMonthly(monthly float64) Cost {
+	return Cost{
+		Hourly:  monthly / HoursInMonth,
+		Monthly: monthly,
+		Annual:  monthly * MonthsInYear,
+	}
+}
+
+// Add returns the sum of both costs.
+func (c Cost) Add(other Cost) Cost {
+	return Cost{
+		Hourly:  c.Hourly + other.Hourly,
+		Monthly: c.Monthly + other.Monthly,
+		Annual:  c.Annual + other.Annual,
+	}
+}
+
+// Rate returns the hourly rate of a public IPv4 address in the region.
+func (m *Model) Rate(region string) float64 {
+	if rate, ok := m.RegionHourlyRates[region]; ok {
+		return rate
+	}
+	return m.HourlyRate
+}
+
+// Cost returns the cost of the given number of public IPs in the region.
+func (m *Model) Cost(region string, ipCount int) Cost {
+	return FromMonthly(m.Rate(region) * HoursInMonth * float64(ipCount))
+}
+
+// MonthlyCost returns the monthly cost of the given number of public IPs in
+// the region.
+func (m *Model) MonthlyCost(region string, ipCount int) float64 {
+	return m.Cost(region, ipCount).Monthly
+}
+
+// IdleEIPMonthlySurcharge returns the additional monthly cost of an Elastic IP
+// that isn't associated with any resource.
+func (m *Model) IdleEIPMonthlySurcharge(region string) float64 {
+	return m.MonthlyCost(region, 1)
+}
+
+// NATGatewayMonthlyCost returns the monthly cost of a NAT gateway, without its
+// data processing and public IPs.
+func (m *Model) NATGatewayMonthlyCost() float64 {
+	return m.NATGatewayHourlyRate * HoursInMonth
+}
+
+// FreeTierCredit returns the monthly amount covered by the Free Tier of an
+// account using the given number of charged public IPs in each region. The
+// Free Tier hours are valued at the rates of the regions, starting with the
+// cheapest one so that the credit is never overstated, and can't exceed the
+// hours of the IPs. The BYOIP and customer-owned IPs are free anyway, so they
+// must be left out.
+func (m *Model) FreeTierCredit(chargedIPs map[string]int) float64 {
+	regions := make([]string, 0, len(chargedIPs))
+	for region := range chargedIPs {
+		regions = append(regions, region)
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		if m.Rate(regions[i]) != m.Rate(regions[j]) {
+			return m.Rate(regions[i]) < m.Rate(regions[j])
+		}
+		return regions[i] < regions[j]
+	})
+
+	remaining, credit := m.FreeTierHours, 0.0
+	for _, region := range regions {
+		hours := math.Min(remaining, float64(chargedIPs[region])*HoursInMonth)
+		credit += hours * m.Rate(region)
+		remaining -= hours
+	}
+	return credit
+}
+
+// Format returns the amount along with the model's currency.
+func (m *Model) Format(amount float64) string {
+	return FormatAmount(m.Currency, amount)
+}
+
+// FormatAmount returns the amount along with the currency, using $ for USD.
+func FormatAmount(currency string, amount float64) string {
+	if currency == "" || currency == DefaultCurrency {
+		return fmt.Sprintf("$%.2f", amount)
+	}
+	return fmt.Sprintf("%.2f %s", amount, currency)
+}
+
+// ParseRegionRates parses a comma separated list of region=rate overrides,
+// such as "us-east-1=0.005,eu-central-1=0.006".
+func ParseRegionRates(value string) (map[string]float64, error) {
+	rates := map[string]float64{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		region, rate, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid region rate %q, expected region=rate", item)
+		}
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(rate), 64)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid rate for region %s: %q", region, rate)
+		}
+		rates[strings.TrimSpace(region)] = parsed
+	}
+	return rates, nil
+}
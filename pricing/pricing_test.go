@@ -0,0 +1,110 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package pricing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func almostEqual(a, b float64) bool {
+	return a > b-1e-9 && a < b+1e-9
+}
+
+func TestMonthlyCost(t *testing.T) {
+	m := Default()
+	m.RegionHourlyRates = map[string]float64{"eu-central-1": 0.006}
+
+	tests := []struct {
+		region  string
+		ipCount int
+		want    float64
+	}{
+		{"us-east-1", 0, 0},
+		{"us-east-1", 1, 3.65},
+		{"us-east-1", 3, 10.95},
+		{"eu-central-1", 1, 4.38},
+	}
+
+	for _, tt := range tests {
+		if got := m.MonthlyCost(tt.region, tt.ipCount); !almostEqual(got, tt.want) {
+			t.Errorf("MonthlyCost(%s, %d) = %v, want %v", tt.region, tt.ipCount, got, tt.want)
+		}
+	}
+}
+
+func TestCost(t *testing.T) {
+	got := Default().Cost("us-east-1", 2)
+	if !almostEqual(got.Hourly, 0.01) || !almostEqual(got.Monthly, 7.3) || !almostEqual(got.Annual, 87.6) {
+		t.Errorf("Cost() = %+v, want 0.01 hourly, 7.30 monthly and 87.60 annual", got)
+	}
+}
+
+func TestFreeTierCredit(t *testing.T) {
+	m := Default()
+	if got := m.FreeTierCredit(map[string]int{"us-east-1": 5}); got != 0 {
+		t.Errorf("FreeTierCredit() without Free Tier = %v, want 0", got)
+	}
+
+	m.FreeTierHours = FreeTierHours
+	m.RegionHourlyRates = map[string]float64{"eu-central-1": 0.004, "sa-east-1": 0.006}
+	tests := []struct {
+		chargedIPs map[string]int
+		want       float64
+	}{
+		{nil, 0},
+		{map[string]int{"us-east-1": 1}, 3.65},
+		{map[string]int{"us-east-1": 5}, 3.75},
+		// The regional rates value the hours
+		{map[string]int{"eu-central-1": 1}, 2.92},
+		{map[string]int{"sa-east-1": 5}, 4.50},
+		// The cheapest region is covered first, the remaining 20 hours at
+		// the default rate
+		{map[string]int{"us-east-1": 1, "eu-central-1": 1}, 2.92 + 0.10},
+	}
+	for _, tt := range tests {
+		if got := m.FreeTierCredit(tt.chargedIPs); !almostEqual(got, tt.want) {
+			t.Errorf("FreeTierCredit(%v) = %v, want %v", tt.chargedIPs, got, tt.want)
+		}
+	}
+}
+
+func TestParseRegionRates(t *testing.T) {
+	got, err := ParseRegionRates("us-east-1=0.005, eu-central-1=0.006,")
+	if err != nil {
+		t.Fatalf("ParseRegionRates() error = %v", err)
+	}
+	want := map[string]float64{"us-east-1": 0.005, "eu-central-1": 0.006}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRegionRates() = %v, want %v", got, want)
+	}
+
+	for _, invalid := range []string{"us-east-1", "us-east-1=cheap", "us-east-1=-1"} {
+		if _, err := ParseRegionRates(invalid); err == nil {
+			t.Errorf("ParseRegionRates(%q) succeeded, want an error", invalid)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	if got := FormatAmount("USD", 3.65); got != "$3.65" {
+		t.Errorf("FormatAmount(USD) = %s, want $3.65", got)
+	}
+	if got := FormatAmount("EUR", 3.4); got != "3.40 EUR" {
+		t.Errorf("FormatAmount(EUR) = %s, want 3.40 EUR", got)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+func formatCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// WriteCSVFiles writes each of the report tables to its own CSV file in
+// outputDir, creating the directory if needed.
+func (r *Report) WriteCSVFiles(outputDir string) error {
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	for _, table := range r.Tables() {
+		path := filepath.Join(outputDir, table.Name+".csv")
+		if err := writeCSVFile(path, table.Headers, table.Rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCSVFile(path string, headers []string, rows [][]interface{}) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.Write(headers); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = formatCSVValue(value)
+		}
+		if err := w.Write(record); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package report
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/globalaccelerator"
+	"github.com/aws/aws-sdk-go-v2/service/opensearch"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/redshift"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/collector"
+)
+
+var errAccessDenied = errors.New("AccessDenied")
+
+// deniedEC2 only allows listing the regions and the ENIs, like a role missing
+// most of the permissions needed by the collectors.
+type deniedEC2 struct {
+	networkInterfaces []types.NetworkInterface
+}
+
+func (f *deniedEC2) DescribeRegions(ctx context.Context, params *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error) {
+	return &ec2.DescribeRegionsOutput{Regions: []types.Region{{RegionName: aws.String("us-east-1")}}}, nil
+}
+
+func (f *deniedEC2) DescribeNetworkInterfaces(ctx context.Context, params *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	if len(params.Filters) > 0 {
+		return &ec2.DescribeNetworkInterfacesOutput{}, nil
+	}
+	return &ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: f.networkInterfaces}, nil
+}
+
+func (f *deniedEC2) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	return nil, errAccessDenied
+}
+
+func (f *deniedEC2) DescribeAddresses(ctx context.Context, params *ec2.DescribeAddressesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeAddressesOutput, error) {
+	return nil, errAccessDenied
+}
+
+func (f *deniedEC2) DescribeNatGateways(ctx context.Context, params *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+	return nil, errAccessDenied
+}
+
+func (f *deniedEC2) DescribeVpnConnections(ctx context.Context, params *ec2.DescribeVpnConnectionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpnConnectionsOutput, error) {
+	return nil, errAccessDenied
+}
+
+func (f *deniedEC2) DescribeClientVpnEndpoints(ctx context.Context, params *ec2.DescribeClientVpnEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeClientVpnEndpointsOutput, error) {
+	return nil, errAccessDenied
+}
+
+func (f *deniedEC2) DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	return nil, errAccessDenied
+}
+
+func (f *deniedEC2) DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	return nil, errAccessDenied
+}
+
+func (f *deniedEC2) ModifySubnetAttribute(ctx context.Context, params *ec2.ModifySubnetAttributeInput, optFns ...func(*ec2.Options)) (*ec2.ModifySubnetAttributeOutput, error) {
+	return nil, errAccessDenied
+}
+
+type deniedELB struct{}
+
+func (deniedELB) DescribeLoadBalancers(ctx context.Context, params *elb.DescribeLoadBalancersInput, optFns ...func(*elb.Options)) (*elb.DescribeLoadBalancersOutput, error) {
+	return nil, errAccessDenied
+}
+
+type deniedELBv2 struct{}
+
+func (deniedELBv2) DescribeLoadBalancers(ctx context.Context, params *elbv2.DescribeLoadBalancersInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeLoadBalancersOutput, error) {
+	return nil, errAccessDenied
+}
+
+func (deniedELBv2) DescribeTargetGroups(ctx context.Context, params *elbv2.DescribeTargetGroupsInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeTargetGroupsOutput, error) {
+	return nil, errAccessDenied
+}
+
+type deniedCloudWatch struct{}
+
+func (deniedCloudWatch) GetMetricData(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+	return nil, errAccessDenied
+}
+
+type deniedGlobalAccelerator struct{}
+
+func (deniedGlobalAccelerator) ListAccelerators(ctx context.Context, params *globalaccelerator.ListAcceleratorsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListAcceleratorsOutput, error) {
+	return nil, errAccessDenied
+}
+
+func (deniedGlobalAccelerator) ListCustomRoutingAccelerators(ctx context.Context, params *globalaccelerator.ListCustomRoutingAcceleratorsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListCustomRoutingAcceleratorsOutput, error) {
+	return nil, errAccessDenied
+}
+
+func (deniedGlobalAccelerator) ListListeners(ctx context.Context, params *globalaccelerator.ListListenersInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListListenersOutput, error) {
+	return nil, errAccessDenied
+}
+
+func (deniedGlobalAccelerator) ListCustomRoutingListeners(ctx context.Context, params *globalaccelerator.ListCustomRoutingListenersInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListCustomRoutingListenersOutput, error) {
+	return nil, errAccessDenied
+}
+
+func (deniedGlobalAccelerator) ListEndpointGroups(ctx context.Context, params *globalaccelerator.ListEndpointGroupsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListEndpointGroupsOutput, error) {
+	return nil, errAccessDenied
+}
+
+func (deniedGlobalAccelerator) ListCustomRoutingEndpointGroups(ctx context.Context, params *globalaccelerator.ListCustomRoutingEndpointGroupsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListCustomRoutingEndpointGroupsOutput, error) {
+	return nil, errAccessDenied
+}
+
+func (deniedGlobalAccelerator) ListByoipCidrs(ctx context.Context, params *globalaccelerator.ListByoipCidrsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListByoipCidrsOutput, error) {
+	return nil, errAccessDenied
+}
+
+type deniedRDS struct{}
+
+func (deniedRDS) DescribeDBInstances(ctx context.Context, params *rds.DescribeDBInstancesInput, optFns ...func(*rds.Options)) (*rds.DescribeDBInstancesOutput, error) {
+	return nil, errAccessDenied
+}
+
+func (deniedRDS) DescribeDBClusters(ctx context.Context, params *rds.DescribeDBClustersInput, optFns ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error) {
+	return nil, errAccessDenied
+}
+
+type deniedRedshift struct{}
+
+func (deniedRedshift) DescribeClusters(ctx context.Context, params *redshift.DescribeClustersInput, optFns ...func(*redshift.Options)) (*redshift.DescribeClustersOutput, error) {
+	return nil, errAccessDenied
+}
+
+type deniedOpenSearch struct{}
+
+func (deniedOpenSearch) ListDomainNames(ctx context.Context, params *opensearch.ListDomainNamesInput, optFns ...func(*opensearch.Options)) (*opensearch.ListDomainNamesOutput, error) {
+	return nil, errAccessDenied
+}
+
+func (deniedOpenSearch) DescribeDomains(ctx context.Context, params *opensearch.DescribeDomainsInput, optFns ...func(*opensearch.Options)) (*opensearch.DescribeDomainsOutput, error) {
+	return nil, errAccessDenied
+}
+
+// deniedClients is a collector.ClientFactory whose clients deny all the calls
+// but those of deniedEC2.
+type deniedClients struct {
+	ec2 *deniedEC2
+}
+
+func (f *deniedClients) EC2(region string) collector.EC2API { return f.ec2 }
+
+func (f *deniedClients) ELB(region string) collector.ELBAPI { return deniedELB{} }
+
+func (f *deniedClients) ELBv2(region string) collector.ELBv2API { return deniedELBv2{} }
+
+func (f *deniedClients) CloudWatch(region string) collector.CloudWatchAPI { return deniedCloudWatch{} }
+
+func (f *deniedClients) GlobalAccelerator() collector.GlobalAcceleratorAPI {
+	return deniedGlobalAccelerator{}
+}
+
+func (f *deniedClients) RDS(region string) collector.RDSAPI { return deniedRDS{} }
+
+func (f *deniedClients) Redshift(region string) collector.RedshiftAPI { return deniedRedshift{} }
+
+func (f *deniedClients) OpenSearch(region string) collector.OpenSearchAPI { return deniedOpenSearch{} }
@@ -0,0 +1,238 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatYAML = "yaml"
+	FormatXLSX = "xlsx"
+)
+
+// Table is a resource table with typed cell values, so that each output
+// format can decide how to render them.
+type Table struct {
+	Name    string
+	Title   string
+	Headers []string
+	Rows    [][]interface{}
+}
+
+// Tables returns the report data laid out like the UI tabs, followed by the
+// totals, the per-account, per-profile and per-service totals, the scan
+// statistics and the skipped regions.
+func (r *Report) Tables() []Table {
+	ips := Table{Name: "public_ips", Title: "Public IPs", Headers: []string{"Profile", "Account", "Region", "Public IP", "Status",
+		"Owner Type", "Owner ID", "Owner Name", "ENI ID", "IP Owner", "Sources", "Cost"}}
+	for _, ip := range r.IPs {
+		ips.Rows = append(ips.Rows, []interface{}{ip.Profile, ip.Account, ip.Region, ip.PublicIP, ip.Status,
+			ip.OwnerType, ip.OwnerID, ip.OwnerName, ip.ENIID, ip.IPOwner, strings.Join(ip.Sources, " "), ip.Cost})
+	}
+
+	enis := Table{Name: "enis", Title: "ENIs", Headers: []string{"Profile", "Account", "Region", "Public IP", "Private IP", "ENI ID", "Interface Type",
+		"Service", "Owner ID", "Description", "IP Owner", "Cost"}}
+	for _, eni := range r.ENIs {
+		enis.Rows = append(enis.Rows, []interface{}{eni.Profile, eni.Account, eni.Region, eni.PublicIP, eni.PrivateIP, eni.ENIID, eni.InterfaceType,
+			eni.Service, eni.OwnerID, eni.Description, eni.IPOwner, eni.Cost})
+	}
+
+	instances := Table{Name: "ec2_instances", Title: "EC2 Instances", Headers: []string{"Profile", "Account", "Region", "Name Tag", "Instance State",
+		"Instance ID", "Public IP", "IP Count", "Public IPs", "Uncharged IPs", "VPC ID", "Subnet ID", "Cost"}}
+	for _, instance := range r.EC2Instances {
+		instances.Rows = append(instances.Rows, []interface{}{instance.Profile, instance.Account, instance.Region, instance.NameTag, instance.InstanceState,
+			instance.InstanceID, instance.PublicIP, instance.IPCount, strings.Join(instance.PublicIPs, " "), instance.UnchargedIPCount,
+			instance.VPCID, instance.SubnetID, instance.Cost})
+	}
+
+	window := fmt.Sprintf(" (last %s)", r.Lookback)
+	lbs := Table{Name: "load_balancers", Title: "Load Balancers", Headers: []string{"Profile", "Account", "Region", "Load Balancer Type", "Scheme", "DNS Name",
+		"IP Address Type", "Dual-Stack Candidate", "Dual-Stack Note", "IP Source", "IP Count", "Public IPs", "Uncharged IPs", "Processed Bytes" + window, "Requests" + window, "Peak Active Connections" + window,
+		"New Connections" + window, "Healthy Hosts", "Targets", "IPv4 Cost per GB", "IPv4 Cost per Million Requests", "Cost"}}
+	for _, lb := range r.LoadBalancers {
+		lbs.Rows = append(lbs.Rows, []interface{}{lb.Profile, lb.Account, lb.Region, lb.Type, lb.Scheme, lb.DNSName,
+			lb.IPAddressType, lb.DualStackCandidate, lb.DualStackNote, lb.IPSource, lb.IPCount,
+			strings.Join(lb.PublicIPs, " "), lb.UnchargedIPCount, lb.ProcessedBytes, lb.RequestCount, lb.PeakActiveConnections, lb.NewConnections,
+			lb.HealthyHosts, lb.Targets, lb.CostPerGB, lb.CostPerMillionRequests, lb.Cost})
+	}
+
+	eips := Table{Name: "eips", Title: "Elastic IPs", Headers: []string{"Profile", "Account", "Region", "Name Tag", "Public IP", "Allocation ID",
+		"Association ID", "Attached Resource", "Network Interface", "Private IP", "Domain", "Network Border Group", "Public IPv4 Pool", "IP Owner", "Tags", "Cost"}}
+	for _, eip := range r.EIPs {
+		eips.Rows = append(eips.Rows, []interface{}{eip.Profile, eip.Account, eip.Region, eip.NameTag, eip.PublicIP, eip.AllocationID,
+			eip.AssociationID, eip.AssociationTarget, eip.NetworkInterfaceID, eip.PrivateIP, eip.Domain, eip.NetworkBorderGroup,
+			eip.PublicIPv4Pool, eip.IPOwner, formatTags(eip.Tags), eip.Cost})
+	}
+
+	natGateways := Table{Name: "nat_gateways", Title: "NAT Gateways", Headers: []string{"Profile", "Account", "Region", "NAT Gateway ID", "Name Tag",
+		"State", "Connectivity Type", "VPC ID", "Subnet ID", "Availability Zone", "IP Count", "Public IPs", "Bytes Out to Destination" + window,
+		"Peak Active Connections" + window, "Consolidation Candidate", "Consolidation Note", "Cost"}}
+	for _, natGateway := range r.NATGateways {
+		natGateways.Rows = append(natGateways.Rows, []interface{}{natGateway.Profile, natGateway.Account, natGateway.Region, natGateway.NATGatewayID,
+			natGateway.NameTag, natGateway.State, natGateway.ConnectivityType, natGateway.VPCID, natGateway.SubnetID, natGateway.AvailabilityZone,
+			natGateway.IPCount, strings.Join(natGateway.PublicIPs, " "), natGateway.BytesOutToDestination, natGateway.PeakActiveConnections,
+			natGateway.ConsolidationCandidate, natGateway.ConsolidationNote, natGateway.Cost})
+	}
+
+	accelerators := Table{Name: "global_accelerators", Title: "Global Accelerators", Headers: []string{"Profile", "Account", "Region", "Accelerator ARN",
+		"Name", "Type", "Status", "Enabled", "IP Address Type", "DNS Name", "IP Count", "Public IPs", "Uncharged IPs", "Listeners", "Endpoint Groups", "Cost"}}
+	for _, accelerator := range r.GlobalAccelerators {
+		accelerators.Rows = append(accelerators.Rows, []interface{}{accelerator.Profile, accelerator.Account, accelerator.Region,
+			accelerator.AcceleratorARN, accelerator.Name, accelerator.Type, accelerator.Status, accelerator.Enabled, accelerator.IPAddressType,
+			accelerator.DNSName, accelerator.IPCount, strings.Join(accelerator.PublicIPs, " "), accelerator.UnchargedIPCount,
+			strings.Join(accelerator.Listeners, "; "),
+			strings.Join(accelerator.EndpointGroups, "; "), accelerator.Cost})
+	}
+
+	vpnConnections := Table{Name: "vpn_connections", Title: "VPN Connections", Headers: []string{"Profile", "Account", "Region", "VPN Connection ID",
+		"Name Tag", "State", "Gateway", "Customer Gateway ID", "Outside IP Address Type", "Accelerated", "Tunnel Outside IPs", "IP Count", "Public IPs", "Uncharged IPs", "Cost"}}
+	for _, vpnConnection := range r.VPNConnections {
+		vpnConnections.Rows = append(vpnConnections.Rows, []interface{}{vpnConnection.Profile, vpnConnection.Account, vpnConnection.Region,
+			vpnConnection.VPNConnectionID, vpnConnection.NameTag, vpnConnection.State, vpnConnection.Gateway, vpnConnection.CustomerGatewayID,
+			vpnConnection.OutsideIPAddressType, vpnConnection.Accelerated, strings.Join(vpnConnection.TunnelOutsideIPs, " "), vpnConnection.IPCount,
+			strings.Join(vpnConnection.PublicIPs, " "), vpnConnection.UnchargedIPCount, vpnConnection.Cost})
+	}
+
+	clientVPNEndpoints := Table{Name: "client_vpn_endpoints", Title: "Client VPN Endpoints", Headers: []string{"Profile", "Account", "Region",
+		"Client VPN Endpoint ID", "Name Tag", "Status", "DNS Name", "VPC ID", "Subnet IDs", "ENI IDs", "IP Count", "Public IPs", "Uncharged IPs", "Cost"}}
+	for _, endpoint := range r.ClientVPNEndpoints {
+		clientVPNEndpoints.Rows = append(clientVPNEndpoints.Rows, []interface{}{endpoint.Profile, endpoint.Account, endpoint.Region,
+			endpoint.ClientVPNEndpointID, endpoint.NameTag, endpoint.Status, endpoint.DNSName, endpoint.VPCID, strings.Join(endpoint.SubnetIDs, " "),
+			strings.Join(endpoint.ENIIDs, " "), endpoint.IPCount, strings.Join(endpoint.PublicIPs, " "), endpoint.UnchargedIPCount, endpoint.Cost})
+	}
+
+	databases := Table{Name: "databases", Title: "Databases", Headers: []string{"Profile", "Account", "Region", "Service", "ID", "Cluster",
+		"Engine", "Status", "Endpoint", "VPC ID", "Security Group IDs", "ENI IDs", "IP Count", "Public IPs", "Uncharged IPs", "Public Ingress Groups",
+		"Private Candidate", "Private Note", "Cost"}}
+	for _, database := range r.Databases {
+		databases.Rows = append(databases.Rows, []interface{}{database.Profile, database.Account, database.Region, database.Service, database.ID,
+			database.Cluster, database.Engine, database.Status, database.Endpoint, database.VPCID, strings.Join(database.SecurityGroupIDs, " "),
+			strings.Join(database.ENIIDs, " "), database.IPCount, strings.Join(database.PublicIPs, " "), database.UnchargedIPCount,
+			strings.Join(database.PublicIngressGroups, " "), database.PrivateCandidate, database.PrivateNote, database.Cost})
+	}
+
+	totals := Table{Name: "totals", Title: "Summary", Headers: []string{"Category", "Count", "Cost", "Annual Cost"}}
+	for _, total := range []struct {
+		category string
+		total    CategoryTotal
+	}{
+		{"Total public IPs", r.Totals.Total},
+		{"In use", r.Totals.InUse},
+		{"Idle", r.Totals.Idle},
+		{"Public IPs attached to Elastic Network Interfaces", r.Totals.ENIs},
+		{"EC2 Instances", r.Totals.EC2Instances},
+		{"Load Balancer IPs", r.Totals.LoadBalancers},
+		{"Elastic IPs not attached to instances", r.Totals.EIPs},
+		{"Global Accelerator IPs", r.Totals.GlobalAccelerators},
+		{"VPN IPs", r.Totals.VPNs},
+		{"Database IPs", r.Totals.Databases},
+	} {
+		totals.Rows = append(totals.Rows, []interface{}{total.category, total.total.Count, total.total.Cost, total.total.AnnualCost})
+	}
+	if r.Totals.FreeTierCredit > 0 {
+		totals.Rows = append(totals.Rows, []interface{}{"Free Tier credit", "", -r.Totals.FreeTierCredit, ""})
+	}
+
+	accounts := Table{Name: "account_totals", Title: "Accounts", Headers: append([]string{"Profile", "Account"}, totalsHeaders...)}
+	for _, account := range r.AccountTotals {
+		accounts.Rows = append(accounts.Rows, append([]interface{}{account.Profile, account.Account}, totalsCells(account.Totals)...))
+	}
+
+	profiles := Table{Name: "profile_totals", Title: "Profiles", Headers: append([]string{"Profile"}, totalsHeaders...)}
+	for _, profile := range r.ProfileTotals {
+		profiles.Rows = append(profiles.Rows, append([]interface{}{profile.Profile}, totalsCells(profile.Totals)...))
+	}
+
+	services := Table{Name: "service_totals", Title: "Services", Headers: []string{"Service", "ENI IPs", "Cost", "Annual Cost"}}
+	for _, service := range r.ServiceTotals {
+		services.Rows = append(services.Rows, []interface{}{service.Service, service.Count, service.Cost, service.AnnualCost})
+	}
+
+	stats := Table{Name: "scan_stats", Title: "Scan Details", Headers: []string{"Profile", "Account", "Region", "Operation", "Pages", "Items"}}
+	for _, stat := range r.ScanStats {
+		stats.Rows = append(stats.Rows, []interface{}{stat.Profile, stat.Account, stat.Region, stat.Operation, stat.Pages, stat.Items})
+	}
+
+	skipped := Table{Name: "skipped_regions", Title: "Skipped Regions", Headers: []string{"Profile", "Account", "Region", "Reason"}}
+	for _, region := range r.SkippedRegions {
+		skipped.Rows = append(skipped.Rows, []interface{}{region.Profile, region.Account, region.Region, region.Reason})
+	}
+
+	return []Table{ips, enis, instances, lbs, eips, natGateways, accelerators, vpnConnections, clientVPNEndpoints, databases,
+		totals, accounts, profiles, services, stats, skipped}
+}
+
+var totalsHeaders = []string{"Public IPs", "Total Cost", "Idle IPs", "Idle Cost", "ENI IPs", "ENI Cost", "EC2 Instances", "EC2 Cost",
+	"Load Balancer IPs", "Load Balancer Cost", "Elastic IPs", "Elastic IP Cost",
+	"Global Accelerator IPs", "Global Accelerator Cost", "VPN IPs", "VPN Cost",
+	"Database IPs", "Database Cost"}
+
+func totalsCells(t Totals) []interface{} {
+	return []interface{}{
+		t.Total.Count, t.Total.Cost,
+		t.Idle.Count, t.Idle.Cost,
+		t.ENIs.Count, t.ENIs.Cost,
+		t.EC2Instances.Count, t.EC2Instances.Cost,
+		t.LoadBalancers.Count, t.LoadBalancers.Cost,
+		t.EIPs.Count, t.EIPs.Cost,
+		t.GlobalAccelerators.Count, t.GlobalAccelerators.Cost,
+		t.VPNs.Count, t.VPNs.Cost,
+		t.Databases.Count, t.Databases.Cost,
+	}
+}
+
+// WriteJSON writes the report as a single indented JSON document.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("failed to write json output: %w", err)
+	}
+	return nil
+}
+
+// WriteYAML writes the report as a single YAML document.
+func (r *Report) WriteYAML(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("failed to write yaml output: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to write yaml output: %w", err)
+	}
+	return nil
+}
+
+// formatTags returns the tags as "key=value" pairs sorted by key
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package report
+
+import (
+	"strings"
+
+	"github.com/leanercloud/aws-ipv4-cost-viewer/collector"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/pricing"
+)
+
+// Statuses of the public IPs in the ledger
+const (
+	IPStatusInUse = "in-use"
+	IPStatusIdle  = "idle"
+)
+
+// Types of the resources owning the public IPs in the ledger
+const (
+	OwnerTypeEC2Instance       = "EC2 Instance"
+	OwnerTypeLoadBalancer      = "Load Balancer"
+	OwnerTypeNetworkInterface  = "Network Interface"
+	OwnerTypeElasticIP         = "Elastic IP"
+	OwnerTypeGlobalAccelerator = "Global Accelerator"
+	OwnerTypeVPNConnection     = "VPN Connection"
+	OwnerTypeClientVPNEndpoint = "Client VPN Endpoint"
+	OwnerTypeDatabase          = "Database"
+)
+
+// Sources of the public IPs in the ledger, the collectors that found them
+const (
+	SourceENI               = "eni"
+	SourceEC2Instance       = "ec2_instance"
+	SourceLoadBalancer      = "load_balancer"
+	SourceEIP               = "eip"
+	SourceGlobalAccelerator = "global_accelerator"
+	SourceVPNConnection     = "vpn_connection"
+	SourceClientVPNEndpoint = "client_vpn_endpoint"
+	SourceDatabase          = "database"
+)
+
+// PublicIP is a single public IPv4 address, merged from all the collectors
+// that found it and attributed to exactly one owning resource, so that it's
+// only counted once in the totals.
+type PublicIP struct {
+	Profile   string   `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Account   string   `json:"account" yaml:"account"`
+	Region    string   `json:"region" yaml:"region"`
+	PublicIP  string   `json:"public_ip" yaml:"public_ip"`
+	Status    string   `json:"status" yaml:"status"`
+	OwnerType string   `json:"owner_type" yaml:"owner_type"`
+	OwnerID   string   `json:"owner_id" yaml:"owner_id"`
+	OwnerName string   `json:"owner_name" yaml:"owner_name"`
+	ENIID     string   `json:"eni_id" yaml:"eni_id"`
+	IPOwner   string   `json:"ip_owner" yaml:"ip_owner"`
+	Sources   []string `json:"sources" yaml:"sources"`
+	Cost      float64  `json:"cost" yaml:"cost"`
+
+	ownerPriority int
+}
+
+// Owners found by more specific collectors take precedence, for example the
+// instance using an ENI over the ENI itself.
+var ownerPriorities = map[string]int{
+	OwnerTypeNetworkInterface:  1,
+	OwnerTypeLoadBalancer:      3,
+	OwnerTypeGlobalAccelerator: 3,
+	OwnerTypeVPNConnection:     3,
+	OwnerTypeClientVPNEndpoint: 3,
+	OwnerTypeDatabase:          3,
+	OwnerTypeEC2Instance:       4,
+}
+
+// Priority of the resources EIPs are associated to, such as NAT gateways
+const associationTargetPriority = 2
+
+type ledgerKey struct {
+	region   string
+	publicIP string
+}
+
+type ledger struct {
+	ips   map[ledgerKey]*PublicIP
+	order []ledgerKey
+}
+
+func (l *ledger) record(profile, account, region, publicIP, source string) *PublicIP {
+	key := ledgerKey{region: region, publicIP: publicIP}
+	ip, ok := l.ips[key]
+	if !ok {
+		ip = &PublicIP{Profile: profile, Account: account, Region: region, PublicIP: publicIP}
+		l.ips[key] = ip
+		l.order = append(l.order, key)
+	}
+	for _, existing := range ip.Sources {
+		if existing == source {
+			return ip
+		}
+	}
+	ip.Sources = append(ip.Sources, source)
+	return ip
+}
+
+func (ip *PublicIP) setOwner(priority int, ownerType, ownerID, ownerName string) {
+	if priority <= ip.ownerPriority {
+		return
+	}
+	ip.ownerPriority = priority
+	ip.OwnerType = ownerType
+	ip.OwnerID = ownerID
+	ip.OwnerName = ownerName
+}
+
+// setIPOwner keeps the owners other than Amazon, which only some of the
+// collectors can tell.
+func (ip *PublicIP) setIPOwner(owner string) {
+	if ip.IPOwner == "" || pricing.Charged(ip.IPOwner) {
+		ip.IPOwner = owner
+	}
+}
+
+// buildLedger merges the data of all the collectors into one record for each
+// public IP and region. The IPs that are only known as Elastic IPs without an
+// association are idle, all the others are in use. The costs are computed for
+// each IP once, with the idle EIP surcharge on top for the idle ones, and
+// none for the IPs that aren't owned by Amazon.
+func (r *Report) buildLedger(model *pricing.Model) []PublicIP {
+	l := ledger{ips: map[ledgerKey]*PublicIP{}}
+
+	for _, eni := range r.ENIs {
+		ip := l.record(eni.Profile, eni.Account, eni.Region, eni.PublicIP, SourceENI)
+		ip.ENIID = eni.ENIID
+		ip.setIPOwner(eni.IPOwner)
+		ownerType, ownerID := OwnerTypeNetworkInterface, eni.ENIID
+		if eni.Service != "" && eni.Service != collector.ServiceUnknown {
+			ownerType, ownerID = eni.Service, eni.OwnerID
+		}
+		ip.setOwner(ownerPriorities[OwnerTypeNetworkInterface], ownerType, ownerID, "")
+	}
+	for _, instance := range r.EC2Instances {
+		for _, publicIP := range instance.PublicIPs {
+			ip := l.record(instance.Profile, instance.Account, instance.Region, publicIP, SourceEC2Instance)
+			ip.setOwner(ownerPriorities[OwnerTypeEC2Instance], OwnerTypeEC2Instance, instance.InstanceID, instance.NameTag)
+		}
+	}
+	for _, lb := range r.LoadBalancers {
+		for _, publicIP := range lb.PublicIPs {
+			ip := l.record(lb.Profile, lb.Account, lb.Region, publicIP, SourceLoadBalancer)
+			ip.setOwner(ownerPriorities[OwnerTypeLoadBalancer], OwnerTypeLoadBalancer, lb.DNSName, lb.Type)
+		}
+	}
+	for _, accelerator := range r.GlobalAccelerators {
+		for _, publicIP := range accelerator.PublicIPs {
+			ip := l.record(accelerator.Profile, accelerator.Account, accelerator.Region, publicIP, SourceGlobalAccelerator)
+			ip.setOwner(ownerPriorities[OwnerTypeGlobalAccelerator], OwnerTypeGlobalAccelerator, accelerator.AcceleratorARN, accelerator.Name)
+		}
+	}
+	for _, vpnConnection := range r.VPNConnections {
+		for _, publicIP := range vpnConnection.PublicIPs {
+			ip := l.record(vpnConnection.Profile, vpnConnection.Account, vpnConnection.Region, publicIP, SourceVPNConnection)
+			ip.setOwner(ownerPriorities[OwnerTypeVPNConnection], OwnerTypeVPNConnection, vpnConnection.VPNConnectionID, vpnConnection.NameTag)
+		}
+	}
+	for _, endpoint := range r.ClientVPNEndpoints {
+		for _, publicIP := range endpoint.PublicIPs {
+			ip := l.record(endpoint.Profile, endpoint.Account, endpoint.Region, publicIP, SourceClientVPNEndpoint)
+			ip.setOwner(ownerPriorities[OwnerTypeClientVPNEndpoint], OwnerTypeClientVPNEndpoint, endpoint.ClientVPNEndpointID, endpoint.NameTag)
+		}
+	}
+	// The databases are owned by their service, such as RDS
+	for _, database := range r.Databases {
+		for _, publicIP := range database.PublicIPs {
+			ip := l.record(database.Profile, database.Account, database.Region, publicIP, SourceDatabase)
+			ip.setOwner(ownerPriorities[OwnerTypeDatabase], database.Service, database.ID, database.Cluster)
+		}
+	}
+
+	eipNames := map[ledgerKey]string{}
+	for _, eip := range r.EIPs {
+		ip := l.record(eip.Profile, eip.Account, eip.Region, eip.PublicIP, SourceEIP)
+		eipNames[ledgerKey{region: eip.Region, publicIP: eip.PublicIP}] = eip.NameTag
+		ip.setIPOwner(eip.IPOwner)
+		if eip.AssociationTarget != "" {
+			targetType, targetID, _ := strings.Cut(eip.AssociationTarget, ": ")
+			ip.setOwner(associationTargetPriority, targetType, targetID, eip.NameTag)
+		}
+	}
+
+	ips := make([]PublicIP, 0, len(l.order))
+	for _, key := range l.order {
+		ip := l.ips[key]
+		ip.Status = IPStatusInUse
+		if ip.IPOwner == "" {
+			ip.IPOwner = pricing.IPOwnerAmazon
+		}
+		ip.Cost = model.MonthlyCost(ip.Region, 1)
+		// Only unassociated EIPs, not seen by any other collector, have no owner
+		if ip.OwnerType == "" {
+			ip.Status = IPStatusIdle
+			ip.OwnerType = OwnerTypeElasticIP
+			ip.OwnerID = ip.PublicIP
+			ip.OwnerName = eipNames[key]
+			ip.Cost += model.IdleEIPMonthlySurcharge(ip.Region)
+		}
+		if !pricing.Charged(ip.IPOwner) {
+			ip.Cost = 0
+		}
+		ips = append(ips, *ip)
+	}
+	return ips
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package report
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/leanercloud/aws-ipv4-cost-viewer/collector"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/pricing"
+)
+
+func TestBuildLedger(t *testing.T) {
+	const account, region = "111111111111", "us-east-1"
+	r := &Report{
+		ENIs: []collector.ENIInfo{
+			{Account: account, Region: region, PublicIP: "1.1.1.1", ENIID: "eni-instance"},
+			{Account: account, Region: region, PublicIP: "2.2.2.2", ENIID: "eni-lb"},
+			{Account: account, Region: region, PublicIP: "3.3.3.3", ENIID: "eni-nat"},
+		},
+		EC2Instances: []collector.EC2InstanceInfo{
+			{Account: account, Region: region, PublicIP: "1.1.1.1", PublicIPs: []string{"1.1.1.1"}, IPCount: 1, InstanceID: "i-1", NameTag: "web"},
+		},
+		LoadBalancers: []collector.LoadBalancerInfo{
+			{Account: account, Region: region, Type: "network", DNSName: "nlb.example.com", PublicIPs: []string{"2.2.2.2"}},
+		},
+		EIPs: []collector.EIPInfo{
+			{Account: account, Region: region, PublicIP: "1.1.1.1", AssociationTarget: "Instance: i-1"},
+			{Account: account, Region: region, PublicIP: "3.3.3.3", NameTag: "nat", AssociationTarget: "NAT Gateway: nat-1"},
+			{Account: account, Region: region, PublicIP: "4.4.4.4", NameTag: "spare"},
+		},
+	}
+
+	model := pricing.Default()
+	ips := r.buildLedger(model)
+
+	inUse, idle := model.MonthlyCost(region, 1), model.MonthlyCost(region, 1)+model.IdleEIPMonthlySurcharge(region)
+	want := []PublicIP{
+		{Account: account, Region: region, PublicIP: "1.1.1.1", Status: IPStatusInUse, OwnerType: OwnerTypeEC2Instance, OwnerID: "i-1", OwnerName: "web",
+			ENIID: "eni-instance", IPOwner: pricing.IPOwnerAmazon, Sources: []string{SourceENI, SourceEC2Instance, SourceEIP}, Cost: inUse, ownerPriority: 4},
+		{Account: account, Region: region, PublicIP: "2.2.2.2", Status: IPStatusInUse, OwnerType: OwnerTypeLoadBalancer, OwnerID: "nlb.example.com", OwnerName: "network",
+			ENIID: "eni-lb", IPOwner: pricing.IPOwnerAmazon, Sources: []string{SourceENI, SourceLoadBalancer}, Cost: inUse, ownerPriority: 3},
+		{Account: account, Region: region, PublicIP: "3.3.3.3", Status: IPStatusInUse, OwnerType: "NAT Gateway", OwnerID: "nat-1", OwnerName: "nat",
+			ENIID: "eni-nat", IPOwner: pricing.IPOwnerAmazon, Sources: []string{SourceENI, SourceEIP}, Cost: inUse, ownerPriority: 2},
+		{Account: account, Region: region, PublicIP: "4.4.4.4", Status: IPStatusIdle, OwnerType: OwnerTypeElasticIP, OwnerID: "4.4.4.4", OwnerName: "spare",
+			IPOwner: pricing.IPOwnerAmazon, Sources: []string{SourceEIP}, Cost: idle},
+	}
+	if !reflect.DeepEqual(ips, want) {
+		t.Errorf("buildLedger() = %+v, want %+v", ips, want)
+	}
+}
+
+func TestBYOIPNotCharged(t *testing.T) {
+	const account, region = "111111111111", "us-east-1"
+	// The collectors leave the BYOIP addresses out of the costs
+	r := &Report{
+		ENIs: []collector.ENIInfo{
+			{Account: account, Region: region, PublicIP: "1.1.1.1", IPOwner: pricing.IPOwnerAmazon, Cost: 3.65},
+			{Account: account, Region: region, PublicIP: "2.2.2.2", IPOwner: pricing.IPOwnerBYOIP},
+		},
+		LoadBalancers: []collector.LoadBalancerInfo{
+			{Account: account, Region: region, Type: "network", IPCount: 2, PublicIPs: []string{"1.1.1.1", "2.2.2.2"},
+				UnchargedIPCount: 1, Cost: 3.65},
+		},
+		EIPs: []collector.EIPInfo{
+			{Account: account, Region: region, PublicIP: "2.2.2.2", AssociationTarget: "Network Load Balancer: net/nlb/1", Associated: true,
+				IPOwner: pricing.IPOwnerBYOIP},
+			{Account: account, Region: region, PublicIP: "3.3.3.3", IPOwner: pricing.IPOwnerCustomerOwned},
+		},
+	}
+
+	model := pricing.Default()
+	r.finalize(model, scope{account: account})
+
+	for _, ip := range r.IPs {
+		if charged := pricing.Charged(ip.IPOwner); charged != (ip.Cost > 0) {
+			t.Errorf("public IP %s owned by %s costs %v", ip.PublicIP, ip.IPOwner, ip.Cost)
+		}
+	}
+	if r.Totals.Total.Count != 3 || r.Totals.Total.Cost != model.MonthlyCost(region, 1) {
+		t.Errorf("Totals = %+v, want 3 public IPs of which only one charged", r.Totals.Total)
+	}
+	if r.Totals.LoadBalancers.Cost != r.Totals.Total.Cost {
+		t.Errorf("load balancer cost = %v, want the ledger total %v", r.Totals.LoadBalancers.Cost, r.Totals.Total.Cost)
+	}
+}
+
+func TestLedgerTotals(t *testing.T) {
+	const account, region = "111111111111", "us-east-1"
+	r := &Report{
+		ENIs: []collector.ENIInfo{
+			{Account: account, Region: region, PublicIP: "1.1.1.1", Cost: 3.65},
+		},
+		EC2Instances: []collector.EC2InstanceInfo{
+			{Account: account, Region: region, PublicIP: "1.1.1.1", PublicIPs: []string{"1.1.1.1"}, IPCount: 1, Cost: 3.65},
+		},
+		EIPs: []collector.EIPInfo{
+			{Account: account, Region: region, PublicIP: "1.1.1.1", AssociationTarget: "Instance: i-1", Cost: 3.65},
+			{Account: account, Region: region, PublicIP: "2.2.2.2", Cost: 3.65},
+		},
+	}
+
+	model := pricing.Default()
+	r.finalize(model, scope{account: account})
+
+	// The instance IP is found by three collectors but only counted once
+	if r.Totals.Total.Count != 2 || r.Totals.InUse.Count != 1 || r.Totals.Idle.Count != 1 {
+		t.Errorf("Totals = %+v, want 2 public IPs, 1 in use and 1 idle", r.Totals)
+	}
+	if want := 2*model.MonthlyCost(region, 1) + model.IdleEIPMonthlySurcharge(region); r.Totals.Total.Cost < want-1e-9 || r.Totals.Total.Cost > want+1e-9 {
+		t.Errorf("Total cost = %v, want %v", r.Totals.Total.Cost, want)
+	}
+}
+
+func TestGlobalAcceleratorIPs(t *testing.T) {
+	const account = "111111111111"
+	r := &Report{
+		GlobalAccelerators: []collector.GlobalAcceleratorInfo{
+			{Account: account, Region: collector.GlobalAcceleratorRegion, AcceleratorARN: "arn:web", Name: "web", IPCount: 2,
+				PublicIPs: []string{"75.2.0.1", "99.83.0.1"}, Cost: 7.3},
+		},
+	}
+
+	model := pricing.Default()
+	r.finalize(model, scope{account: account})
+
+	for _, ip := range r.IPs {
+		if ip.Status != IPStatusInUse || ip.OwnerType != OwnerTypeGlobalAccelerator || ip.OwnerID != "arn:web" || ip.OwnerName != "web" ||
+			ip.Region != collector.GlobalAcceleratorRegion {
+			t.Errorf("public IP %s = %+v, want it owned by the accelerator", ip.PublicIP, ip)
+		}
+	}
+	if r.Totals.Total.Count != 2 || r.Totals.GlobalAccelerators.Count != 2 || r.Totals.GlobalAccelerators.Cost != 7.3 {
+		t.Errorf("Totals = %+v, want the 2 accelerator IPs", r.Totals)
+	}
+}
+
+func TestVPNIPs(t *testing.T) {
+	const account, region = "111111111111", "us-east-1"
+	r := &Report{
+		ENIs: []collector.ENIInfo{
+			{Account: account, Region: region, PublicIP: "5.5.5.1", ENIID: "eni-vpn", Service: collector.ServiceUnknown, Cost: 3.65},
+		},
+		VPNConnections: []collector.VPNConnectionInfo{
+			{Account: account, Region: region, VPNConnectionID: "vpn-1", NameTag: "office", IPCount: 2, PublicIPs: []string{"3.3.3.1", "3.3.3.2"},
+				TunnelOutsideIPs: []string{"3.3.3.1", "3.3.3.2"}, Cost: 7.3},
+			{Account: account, Region: region, VPNConnectionID: "vpn-private", OutsideIPAddressType: collector.OutsideIPAddressTypePrivate,
+				TunnelOutsideIPs: []string{"10.0.0.1"}},
+		},
+		ClientVPNEndpoints: []collector.ClientVPNEndpointInfo{
+			{Account: account, Region: region, ClientVPNEndpointID: "cvpn-endpoint-1", ENIIDs: []string{"eni-vpn"}, IPCount: 1,
+				PublicIPs: []string{"5.5.5.1"}, Cost: 3.65},
+		},
+	}
+
+	model := pricing.Default()
+	r.finalize(model, scope{account: account})
+
+	owners := map[string]string{}
+	for _, ip := range r.IPs {
+		owners[ip.PublicIP] = ip.OwnerType + ": " + ip.OwnerID
+	}
+	want := map[string]string{
+		"3.3.3.1": "VPN Connection: vpn-1",
+		"3.3.3.2": "VPN Connection: vpn-1",
+		"5.5.5.1": "Client VPN Endpoint: cvpn-endpoint-1",
+	}
+	if !reflect.DeepEqual(owners, want) {
+		t.Errorf("public IP owners = %v, want %v", owners, want)
+	}
+	if r.Totals.Total.Count != 3 || r.Totals.VPNs.Count != 3 || r.Totals.VPNs.Cost != 10.95 {
+		t.Errorf("Totals = %+v, want the 3 public VPN IPs", r.Totals)
+	}
+}
+
+func TestDatabaseIPs(t *testing.T) {
+	const account, region = "111111111111", "us-east-1"
+	r := &Report{
+		ENIs: []collector.ENIInfo{
+			{Account: account, Region: region, PublicIP: "1.1.1.1", ENIID: "eni-app", Service: collector.ServiceRDS, OwnerID: "eni-app", Cost: 3.65},
+			{Account: account, Region: region, PublicIP: "2.2.2.1", ENIID: "eni-warehouse", Service: collector.ServiceRedshift, OwnerID: "eni-warehouse",
+				Cost: 3.65},
+		},
+		Databases: []collector.DatabaseInfo{
+			{Account: account, Region: region, Service: collector.ServiceRDS, ID: "app", Cluster: "analytics", ENIIDs: []string{"eni-app"},
+				IPCount: 1, PublicIPs: []string{"1.1.1.1"}, Cost: 3.65, PrivateCandidate: true},
+			{Account: account, Region: region, Service: collector.ServiceRedshift, ID: "warehouse", ENIIDs: []string{"eni-warehouse"},
+				IPCount: 1, PublicIPs: []string{"2.2.2.1"}, Cost: 3.65},
+			{Account: account, Region: region, Service: collector.ServiceOpenSearch, ID: "logs"},
+		},
+	}
+
+	model := pricing.Default()
+	r.finalize(model, scope{account: account})
+
+	owners := map[string]string{}
+	for _, ip := range r.IPs {
+		owners[ip.PublicIP] = ip.OwnerType + ": " + ip.OwnerID
+	}
+	want := map[string]string{
+		"1.1.1.1": "RDS: app",
+		"2.2.2.1": "Redshift: warehouse",
+	}
+	if !reflect.DeepEqual(owners, want) {
+		t.Errorf("public IP owners = %v, want %v", owners, want)
+	}
+	for _, eni := range r.ENIs {
+		if eni.ENIID == "eni-app" && eni.OwnerID != "app" {
+			t.Errorf("ENI %s owner = %q, want the RDS DB instance", eni.ENIID, eni.OwnerID)
+		}
+	}
+	if r.Totals.Total.Count != 2 || r.Totals.Databases.Count != 2 || r.Totals.Databases.Cost != 7.3 {
+		t.Errorf("Totals = %+v, want the 2 database IPs", r.Totals)
+	}
+}
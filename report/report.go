@@ -0,0 +1,584 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+// Package report gathers the data from all the collectors into a single
+// report and writes it in various formats.
+package report
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/leanercloud/aws-ipv4-cost-viewer/collector"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/pricing"
+)
+
+// TotalsNote explains why the per-category totals overlap and add up to more
+// than the overall total.
+const TotalsNote = "Note: the same IP can be found by several collectors, e.g. the ENI of an instance, so the categories overlap. The total counts each public IP only once, attributed to a single owner"
+
+// CategoryTotal is the number of public IPs of a category and their cost,
+// where Cost is the monthly one.
+type CategoryTotal struct {
+	Count      int     `json:"count" yaml:"count"`
+	Cost       float64 `json:"cost" yaml:"cost"`
+	HourlyCost float64 `json:"hourly_cost" yaml:"hourly_cost"`
+	AnnualCost float64 `json:"annual_cost" yaml:"annual_cost"`
+}
+
+// Totals mirrors the cost summary shown in the Summary tab of the UI. For load
+// balancers the count is the number of IPs, not of load balancers. The
+// categories overlap, while InUse, Idle and Total come from the de-duplicated
+// public IPs and count each of them once.
+type Totals struct {
+	Total         CategoryTotal `json:"total" yaml:"total"`
+	InUse         CategoryTotal `json:"in_use" yaml:"in_use"`
+	Idle          CategoryTotal `json:"idle" yaml:"idle"`
+	ENIs          CategoryTotal `json:"enis" yaml:"enis"`
+	EC2Instances  CategoryTotal `json:"ec2_instances" yaml:"ec2_instances"`
+	LoadBalancers CategoryTotal `json:"load_balancers" yaml:"load_balancers"`
+	EIPs          CategoryTotal `json:"eips" yaml:"eips"`
+	// GlobalAccelerators are the static IPs of the accelerators
+	GlobalAccelerators CategoryTotal `json:"global_accelerators" yaml:"global_accelerators"`
+	// VPNs are the public IPs of the Site-to-Site VPN tunnels and of the
+	// Client VPN endpoints
+	VPNs CategoryTotal `json:"vpns" yaml:"vpns"`
+	// Databases are the public IPs of the publicly accessible RDS DB
+	// instances, Redshift clusters and OpenSearch domains, though the public
+	// OpenSearch domains are served from IPs of the service and add none
+	Databases CategoryTotal `json:"databases" yaml:"databases"`
+	// FreeTierCredit is the monthly amount covered by the Free Tier, when
+	// enabled in the pricing model.
+	FreeTierCredit float64 `json:"free_tier_credit" yaml:"free_tier_credit"`
+}
+
+func (t *Totals) addIP(ip PublicIP) {
+	t.Total.add(1, ip.Cost)
+	if ip.Status == IPStatusIdle {
+		t.Idle.add(1, ip.Cost)
+	} else {
+		t.InUse.add(1, ip.Cost)
+	}
+}
+
+// AccountTotals are the totals of a single account, as scanned through a
+// single profile.
+type AccountTotals struct {
+	Profile string `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Account string `json:"account" yaml:"account"`
+	Totals  `yaml:",inline"`
+}
+
+// ProfileTotals are the totals of all the accounts scanned through a shared
+// config profile.
+type ProfileTotals struct {
+	Profile string `json:"profile" yaml:"profile"`
+	Totals  `yaml:",inline"`
+}
+
+// ServiceTotals are the public IPs of the ENIs owned by an AWS service, such as
+// NAT gateways or ECS tasks, and their cost.
+type ServiceTotals struct {
+	Service       string `json:"service" yaml:"service"`
+	CategoryTotal `yaml:",inline"`
+}
+
+type Report struct {
+	// Currency of all the costs in the report
+	Currency string `json:"currency" yaml:"currency"`
+	// Lookback is the window of the load balancer and NAT gateway metrics,
+	// such as "7d"
+	Lookback string `json:"lookback" yaml:"lookback"`
+	// IPs are all the public IPs found, once each, with their owner
+	IPs           []PublicIP                   `json:"public_ips" yaml:"public_ips"`
+	ENIs          []collector.ENIInfo          `json:"enis" yaml:"enis"`
+	EC2Instances  []collector.EC2InstanceInfo  `json:"ec2_instances" yaml:"ec2_instances"`
+	LoadBalancers []collector.LoadBalancerInfo `json:"load_balancers" yaml:"load_balancers"`
+	EIPs          []collector.EIPInfo          `json:"eips" yaml:"eips"`
+	// NATGateways are only listed, their public IPs are counted with the
+	// ENIs and EIPs
+	NATGateways        []collector.NATGatewayInfo        `json:"nat_gateways" yaml:"nat_gateways"`
+	GlobalAccelerators []collector.GlobalAcceleratorInfo `json:"global_accelerators" yaml:"global_accelerators"`
+	VPNConnections     []collector.VPNConnectionInfo     `json:"vpn_connections" yaml:"vpn_connections"`
+	ClientVPNEndpoints []collector.ClientVPNEndpointInfo `json:"client_vpn_endpoints" yaml:"client_vpn_endpoints"`
+	Databases          []collector.DatabaseInfo          `json:"databases" yaml:"databases"`
+	Totals             Totals                            `json:"totals" yaml:"totals"`
+	AccountTotals      []AccountTotals                   `json:"account_totals" yaml:"account_totals"`
+	ProfileTotals      []ProfileTotals                   `json:"profile_totals,omitempty" yaml:"profile_totals,omitempty"`
+	ServiceTotals      []ServiceTotals                   `json:"service_totals" yaml:"service_totals"`
+	ScanStats          []collector.ScanStat              `json:"scan_stats" yaml:"scan_stats"`
+	// Regions are the regions scanned in any of the accounts.
+	Regions        []string                  `json:"regions" yaml:"regions"`
+	SkippedRegions []collector.SkippedRegion `json:"skipped_regions" yaml:"skipped_regions"`
+	// EnabledRegions are all the regions enabled in any of the accounts, to
+	// choose from when scanning again.
+	EnabledRegions []string `json:"-" yaml:"-"`
+	// Warnings are the errors of the collectors needing permissions that
+	// many roles lack, those of Global Accelerator and of the databases, which
+	// don't fail the scan of the account.
+	Warnings []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+}
+
+// AccountError is returned by CollectAll for the accounts that couldn't be
+// scanned, or only partially, in which case the report still contains the
+// resources that were collected.
+type AccountError struct {
+	Profile string
+	Account string
+	Err     error
+}
+
+func (e *AccountError) Error() string {
+	if e.Profile != "" {
+		return fmt.Sprintf("profile %s account %s: %v", e.Profile, e.Account, e.Err)
+	}
+	return fmt.Sprintf("account %s: %v", e.Account, e.Err)
+}
+
+func (e *AccountError) Unwrap() error {
+	return e.Err
+}
+
+// Collect runs all the collectors in parallel for the given regions and
+// gathers their results into a single report, sorted by IP. The report is
+// returned even when some of the collectors fail, with the resources they
+// did collect, along with their joined errors.
+func Collect(ctx context.Context, c *collector.Collector, regions []string) (*Report, error) {
+	report, err := collect(ctx, c, regions)
+	report.Regions = regions
+	report.Lookback = collector.FormatLookback(c.Lookback)
+	report.finalize(c.Pricing, scope{profile: c.Profile, account: c.Account})
+	return report, err
+}
+
+// CollectAll scans the selected regions with each of the collectors in
+// parallel, usually one for each account or profile, and merges their results
+// into a single report. The accounts that fail, fully or partially, are
+// returned as *AccountError values, along with the report containing all the
+// resources collected, unless none of the accounts could be scanned.
+func CollectAll(ctx context.Context, collectors []*collector.Collector, selection collector.RegionSelection) (*Report, error) {
+	var merged Report
+	var scanned []scope
+	var errs []error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, c := range collectors {
+		wg.Add(1)
+		go func(c *collector.Collector) {
+			defer wg.Done()
+
+			c.ResetScanStats()
+			regions, err := c.SelectRegions(ctx, selection)
+			var report *Report
+			if err == nil {
+				report, err = collect(ctx, c, regions.Selected)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, &AccountError{Profile: c.Profile, Account: c.Account, Err: err})
+			}
+			if report == nil {
+				return
+			}
+			scanned = append(scanned, scope{profile: c.Profile, account: c.Account})
+			merged.ENIs = append(merged.ENIs, report.ENIs...)
+			merged.EC2Instances = append(merged.EC2Instances, report.EC2Instances...)
+			merged.LoadBalancers = append(merged.LoadBalancers, report.LoadBalancers...)
+			merged.EIPs = append(merged.EIPs, report.EIPs...)
+			merged.NATGateways = append(merged.NATGateways, report.NATGateways...)
+			merged.GlobalAccelerators = append(merged.GlobalAccelerators, report.GlobalAccelerators...)
+			merged.VPNConnections = append(merged.VPNConnections, report.VPNConnections...)
+			merged.ClientVPNEndpoints = append(merged.ClientVPNEndpoints, report.ClientVPNEndpoints...)
+			merged.Databases = append(merged.Databases, report.Databases...)
+			merged.ScanStats = append(merged.ScanStats, report.ScanStats...)
+			merged.Regions = append(merged.Regions, regions.Selected...)
+			merged.SkippedRegions = append(merged.SkippedRegions, regions.Skipped...)
+			merged.EnabledRegions = append(merged.EnabledRegions, regions.Enabled...)
+			merged.Warnings = append(merged.Warnings, report.Warnings...)
+		}(c)
+	}
+	wg.Wait()
+
+	if len(collectors) > 0 && len(scanned) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	merged.Regions = uniqueSorted(merged.Regions)
+	merged.EnabledRegions = uniqueSorted(merged.EnabledRegions)
+	sort.Strings(merged.Warnings)
+	sort.SliceStable(merged.SkippedRegions, func(i, j int) bool {
+		a, b := merged.SkippedRegions[i], merged.SkippedRegions[j]
+		if a.Profile != b.Profile {
+			return a.Profile < b.Profile
+		}
+		return a.Account < b.Account
+	})
+	model := pricing.Default()
+	merged.Lookback = collector.FormatLookback(collector.DefaultLookback)
+	if len(collectors) > 0 {
+		model = collectors[0].Pricing
+		merged.Lookback = collector.FormatLookback(collectors[0].Lookback)
+	}
+	merged.finalize(model, scanned...)
+	return &merged, errors.Join(errs...)
+}
+
+// collect returns the report with the results of all the collectors, not yet
+// finalized, along with the errors of those that failed, as each of them still
+// returns what it collected from the other regions.
+func collect(ctx context.Context, c *collector.Collector, regions []string) (*Report, error) {
+	// The results shared by the collectors only last for a scan
+	c.ResetCache()
+
+	var report Report
+	var wg sync.WaitGroup
+	errs := make([]error, 9)
+
+	wg.Add(9)
+	go func() {
+		defer wg.Done()
+		report.ENIs, errs[0] = c.FetchAllENIs(ctx, regions)
+	}()
+	go func() {
+		defer wg.Done()
+		report.EC2Instances, errs[1] = c.FetchAllInstances(ctx, regions)
+	}()
+	go func() {
+		defer wg.Done()
+		report.LoadBalancers, errs[2] = c.FetchAllLoadBalancers(ctx, regions)
+	}()
+	go func() {
+		defer wg.Done()
+		report.EIPs, errs[3] = c.FetchAllEIPs(ctx, regions)
+	}()
+	go func() {
+		defer wg.Done()
+		report.NATGateways, errs[4] = c.FetchAllNATGateways(ctx, regions)
+	}()
+	go func() {
+		defer wg.Done()
+		report.GlobalAccelerators, errs[5] = c.FetchAllGlobalAccelerators(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		report.VPNConnections, errs[6] = c.FetchAllVPNConnections(ctx, regions)
+	}()
+	go func() {
+		defer wg.Done()
+		report.ClientVPNEndpoints, errs[7] = c.FetchAllClientVPNEndpoints(ctx, regions)
+	}()
+	go func() {
+		defer wg.Done()
+		report.Databases, errs[8] = c.FetchAllDatabases(ctx, regions)
+	}()
+	wg.Wait()
+
+	// Global Accelerator and the database services need permissions of their
+	// own, which the roles of the accounts without any of them seldom grant
+	report.Warnings = append(errorMessages(errs[5]), errorMessages(errs[8])...)
+	errs[5], errs[8] = nil, nil
+
+	report.ScanStats = c.ScanStats()
+	return &report, errors.Join(errs...)
+}
+
+// errorMessages returns the messages of the errors joined in err, if any.
+func errorMessages(err error) []string {
+	if err == nil {
+		return nil
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []string{err.Error()}
+	}
+	var messages []string
+	for _, err := range joined.Unwrap() {
+		messages = append(messages, errorMessages(err)...)
+	}
+	return messages
+}
+
+// scope is the profile and account a collector scanned.
+type scope struct {
+	profile string
+	account string
+}
+
+// applyDatabaseOwners attributes the ENIs of the publicly accessible databases
+// to them, as the ENIs of RDS and Redshift don't tell their owner.
+func (r *Report) applyDatabaseOwners() {
+	owners := map[string]string{}
+	for _, database := range r.Databases {
+		for _, eniID := range database.ENIIDs {
+			owners[database.Account+" "+eniID] = database.ID
+		}
+	}
+	for i := range r.ENIs {
+		eni := &r.ENIs[i]
+		if owner, ok := owners[eni.Account+" "+eni.ENIID]; ok {
+			eni.OwnerID = owner
+		}
+	}
+}
+
+// finalize sorts the report data by IP and computes the totals, overall, for
+// each of the scanned accounts and, when profiles were used, for each profile.
+// The costs of the categories have already been computed by the collectors,
+// while those of the de-duplicated public IPs are computed by the pricing
+// model, along with the Free Tier of each account.
+func (r *Report) finalize(model *pricing.Model, scanned ...scope) {
+	r.applyDatabaseOwners()
+	r.IPs = r.buildLedger(model)
+	SortByIP(r.IPs, func(i int) string {
+		return r.IPs[i].PublicIP
+	})
+	SortByIP(r.ENIs, func(i int) string {
+		return r.ENIs[i].PublicIP
+	})
+	SortByIP(r.EC2Instances, func(i int) string {
+		return r.EC2Instances[i].PublicIP
+	})
+	SortByIP(r.LoadBalancers, func(i int) string {
+		if len(r.LoadBalancers[i].PublicIPs) > 0 {
+			return r.LoadBalancers[i].PublicIPs[0]
+		}
+		return ""
+	})
+	SortByIP(r.EIPs, func(i int) string {
+		return r.EIPs[i].PublicIP
+	})
+	SortByIP(r.NATGateways, func(i int) string {
+		if len(r.NATGateways[i].PublicIPs) > 0 {
+			return r.NATGateways[i].PublicIPs[0]
+		}
+		return ""
+	})
+	SortByIP(r.GlobalAccelerators, func(i int) string {
+		if len(r.GlobalAccelerators[i].PublicIPs) > 0 {
+			return r.GlobalAccelerators[i].PublicIPs[0]
+		}
+		return ""
+	})
+	SortByIP(r.VPNConnections, func(i int) string {
+		if len(r.VPNConnections[i].PublicIPs) > 0 {
+			return r.VPNConnections[i].PublicIPs[0]
+		}
+		return ""
+	})
+	SortByIP(r.ClientVPNEndpoints, func(i int) string {
+		if len(r.ClientVPNEndpoints[i].PublicIPs) > 0 {
+			return r.ClientVPNEndpoints[i].PublicIPs[0]
+		}
+		return ""
+	})
+	SortByIP(r.Databases, func(i int) string {
+		if len(r.Databases[i].PublicIPs) > 0 {
+			return r.Databases[i].PublicIPs[0]
+		}
+		return ""
+	})
+	sort.SliceStable(r.ScanStats, func(i, j int) bool {
+		if r.ScanStats[i].Profile != r.ScanStats[j].Profile {
+			return r.ScanStats[i].Profile < r.ScanStats[j].Profile
+		}
+		return r.ScanStats[i].Account < r.ScanStats[j].Account
+	})
+
+	byAccount := map[scope]*Totals{}
+	accountTotals := func(profile, account string) *Totals {
+		key := scope{profile: profile, account: account}
+		if _, ok := byAccount[key]; !ok {
+			byAccount[key] = &Totals{}
+		}
+		return byAccount[key]
+	}
+	for _, s := range scanned {
+		accountTotals(s.profile, s.account)
+	}
+
+	r.Totals = Totals{}
+	byService := map[string]*CategoryTotal{}
+	for _, eni := range r.ENIs {
+		r.Totals.ENIs.add(1, eni.Cost)
+		accountTotals(eni.Profile, eni.Account).ENIs.add(1, eni.Cost)
+
+		if _, ok := byService[eni.Service]; !ok {
+			byService[eni.Service] = &CategoryTotal{}
+		}
+		byService[eni.Service].add(1, eni.Cost)
+	}
+	for _, instance := range r.EC2Instances {
+		r.Totals.EC2Instances.add(instance.IPCount-instance.UnchargedIPCount, instance.Cost)
+		accountTotals(instance.Profile, instance.Account).EC2Instances.add(instance.IPCount-instance.UnchargedIPCount, instance.Cost)
+	}
+	for _, lb := range r.LoadBalancers {
+		r.Totals.LoadBalancers.add(lb.IPCount, lb.Cost)
+		accountTotals(lb.Profile, lb.Account).LoadBalancers.add(lb.IPCount, lb.Cost)
+	}
+	for _, eip := range r.EIPs {
+		// The EIPs of the instances are counted with the instances
+		if eip.InstanceID != "" {
+			continue
+		}
+		r.Totals.EIPs.add(1, eip.Cost)
+		accountTotals(eip.Profile, eip.Account).EIPs.add(1, eip.Cost)
+	}
+	for _, accelerator := range r.GlobalAccelerators {
+		r.Totals.GlobalAccelerators.add(accelerator.IPCount, accelerator.Cost)
+		accountTotals(accelerator.Profile, accelerator.Account).GlobalAccelerators.add(accelerator.IPCount, accelerator.Cost)
+	}
+	for _, vpnConnection := range r.VPNConnections {
+		r.Totals.VPNs.add(vpnConnection.IPCount, vpnConnection.Cost)
+		accountTotals(vpnConnection.Profile, vpnConnection.Account).VPNs.add(vpnConnection.IPCount, vpnConnection.Cost)
+	}
+	for _, endpoint := range r.ClientVPNEndpoints {
+		r.Totals.VPNs.add(endpoint.IPCount, endpoint.Cost)
+		accountTotals(endpoint.Profile, endpoint.Account).VPNs.add(endpoint.IPCount, endpoint.Cost)
+	}
+	for _, database := range r.Databases {
+		r.Totals.Databases.add(database.IPCount, database.Cost)
+		accountTotals(database.Profile, database.Account).Databases.add(database.IPCount, database.Cost)
+	}
+
+	// Only the charged IPs in use are covered by the Free Tier
+	chargedInUse := map[scope]map[string]int{}
+	for _, ip := range r.IPs {
+		r.Totals.addIP(ip)
+		accountTotals(ip.Profile, ip.Account).addIP(ip)
+
+		if ip.Status == IPStatusIdle || !pricing.Charged(ip.IPOwner) {
+			continue
+		}
+		key := scope{profile: ip.Profile, account: ip.Account}
+		if _, ok := chargedInUse[key]; !ok {
+			chargedInUse[key] = map[string]int{}
+		}
+		chargedInUse[key][ip.Region]++
+	}
+
+	r.Currency = model.Currency
+	r.AccountTotals = nil
+	byProfile := map[string]*Totals{}
+	for key, totals := range byAccount {
+		if charged, ok := chargedInUse[key]; ok {
+			totals.FreeTierCredit = model.FreeTierCredit(charged)
+		}
+		r.Totals.FreeTierCredit += totals.FreeTierCredit
+
+		r.AccountTotals = append(r.AccountTotals, AccountTotals{Profile: key.profile, Account: key.account, Totals: *totals})
+
+		if key.profile == "" {
+			continue
+		}
+		if _, ok := byProfile[key.profile]; !ok {
+			byProfile[key.profile] = &Totals{}
+		}
+		byProfile[key.profile].addTotals(*totals)
+	}
+	sort.Slice(r.AccountTotals, func(i, j int) bool {
+		if r.AccountTotals[i].Profile != r.AccountTotals[j].Profile {
+			return r.AccountTotals[i].Profile < r.AccountTotals[j].Profile
+		}
+		return r.AccountTotals[i].Account < r.AccountTotals[j].Account
+	})
+
+	r.ServiceTotals = nil
+	for service, total := range byService {
+		r.ServiceTotals = append(r.ServiceTotals, ServiceTotals{Service: service, CategoryTotal: *total})
+	}
+	// The most expensive services first
+	sort.Slice(r.ServiceTotals, func(i, j int) bool {
+		if r.ServiceTotals[i].Cost != r.ServiceTotals[j].Cost {
+			return r.ServiceTotals[i].Cost > r.ServiceTotals[j].Cost
+		}
+		return r.ServiceTotals[i].Service < r.ServiceTotals[j].Service
+	})
+
+	r.ProfileTotals = nil
+	for profile, totals := range byProfile {
+		r.ProfileTotals = append(r.ProfileTotals, ProfileTotals{Profile: profile, Totals: *totals})
+	}
+	sort.Slice(r.ProfileTotals, func(i, j int) bool {
+		return r.ProfileTotals[i].Profile < r.ProfileTotals[j].Profile
+	})
+}
+
+func (t *Totals) addTotals(other Totals) {
+	t.Total.add(other.Total.Count, other.Total.Cost)
+	t.InUse.add(other.InUse.Count, other.InUse.Cost)
+	t.Idle.add(other.Idle.Count, other.Idle.Cost)
+	t.ENIs.add(other.ENIs.Count, other.ENIs.Cost)
+	t.EC2Instances.add(other.EC2Instances.Count, other.EC2Instances.Cost)
+	t.LoadBalancers.add(other.LoadBalancers.Count, other.LoadBalancers.Cost)
+	t.EIPs.add(other.EIPs.Count, other.EIPs.Cost)
+	t.GlobalAccelerators.add(other.GlobalAccelerators.Count, other.GlobalAccelerators.Cost)
+	t.VPNs.add(other.VPNs.Count, other.VPNs.Cost)
+	t.Databases.add(other.Databases.Count, other.Databases.Cost)
+	t.FreeTierCredit += other.FreeTierCredit
+}
+
+func uniqueSorted(values []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+func (t *CategoryTotal) add(count int, monthlyCost float64) {
+	cost := pricing.FromMonthly(monthlyCost)
+	t.Count += count
+	t.Cost += cost.Monthly
+	t.HourlyCost += cost.Hourly
+	t.AnnualCost += cost.Annual
+}
+
+// SortByIP sorts the data slice by the IP address returned by getIP.
+func SortByIP(data interface{}, getIP func(i int) string) {
+	sort.SliceStable(data, func(i, j int) bool {
+		return lessIP(getIP(i), getIP(j))
+	})
+}
+
+// lessIP orders the IPs by their numeric value, with the empty and unparseable
+// ones last, falling back to their text so that the order is strict.
+func lessIP(s1, s2 string) bool {
+	ip1, ip2 := net.ParseIP(s1), net.ParseIP(s2)
+	switch {
+	case ip1 == nil && ip2 == nil:
+		return s1 < s2
+	case ip1 == nil:
+		return false
+	case ip2 == nil:
+		return true
+	}
+	if c := bytes.Compare(ip1.To16(), ip2.To16()); c != 0 {
+		return c < 0
+	}
+	return s1 < s2
+}
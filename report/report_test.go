@@ -0,0 +1,274 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package report
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/collector"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/pricing"
+)
+
+func TestSortByIP(t *testing.T) {
+	enis := []collector.ENIInfo{
+		{PublicIP: "10.0.0.2"},
+		{PublicIP: ""},
+		{PublicIP: "9.0.0.1"},
+		{PublicIP: "invalid"},
+		{PublicIP: "10.0.0.10"},
+		{PublicIP: ""},
+		{PublicIP: "2001:db8::1"},
+	}
+
+	SortByIP(enis, func(i int) string {
+		return enis[i].PublicIP
+	})
+
+	// The empty and unparseable IPs are last, ordered by their text
+	want := []string{"9.0.0.1", "10.0.0.2", "10.0.0.10", "2001:db8::1", "", "", "invalid"}
+	for i, eni := range enis {
+		if eni.PublicIP != want[i] {
+			t.Errorf("SortByIP() position %d = %s, want %s", i, eni.PublicIP, want[i])
+		}
+	}
+}
+
+func TestWriteCSVFiles(t *testing.T) {
+	r := &Report{
+		Lookback: "7d",
+		LoadBalancers: []collector.LoadBalancerInfo{{
+			Account:       "123456789012",
+			Region:        "us-east-1",
+			Type:          "network",
+			Scheme:        "internet-facing",
+			DNSName:       "nlb.example.com",
+			IPAddressType: "ipv4",
+			IPSource:      "eni",
+			IPCount:       2,
+			PublicIPs:     []string{"1.1.1.1", "2.2.2.2"},
+			Cost:          7.3,
+		}},
+		Totals: Totals{LoadBalancers: CategoryTotal{Count: 2, Cost: 7.3, AnnualCost: 87.6}},
+	}
+
+	dir := t.TempDir()
+	if err := r.WriteCSVFiles(dir); err != nil {
+		t.Fatalf("WriteCSVFiles() error = %v", err)
+	}
+
+	tests := map[string]string{
+		"load_balancers.csv": ",123456789012,us-east-1,network,internet-facing,nlb.example.com,ipv4,false,,eni,2,1.1.1.1 2.2.2.2,0,0,0,0,0,0,0,0.00,0.00,7.30\n",
+		"totals.csv":         "Load Balancer IPs,2,7.30,87.60\n",
+	}
+	for name, wantLine := range tests {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if !strings.Contains(string(data), wantLine) {
+			t.Errorf("%s = %q, want it to contain %q", name, data, wantLine)
+		}
+	}
+}
+
+func TestAccountTotals(t *testing.T) {
+	r := &Report{
+		ENIs: []collector.ENIInfo{
+			{Account: "111111111111", PublicIP: "1.1.1.1", Cost: 3.65},
+			{Account: "222222222222", PublicIP: "2.2.2.2", Cost: 3.65},
+		},
+		LoadBalancers: []collector.LoadBalancerInfo{
+			{Account: "111111111111", IPCount: 2, Cost: 7.3},
+		},
+	}
+
+	r.finalize(pricing.Default(), scope{account: "111111111111"}, scope{account: "222222222222"}, scope{account: "333333333333"})
+
+	want := []AccountTotals{
+		{Account: "111111111111", Totals: Totals{Total: total(1, 3.65), InUse: total(1, 3.65), ENIs: total(1, 3.65), LoadBalancers: total(2, 7.3)}},
+		{Account: "222222222222", Totals: Totals{Total: total(1, 3.65), InUse: total(1, 3.65), ENIs: total(1, 3.65)}},
+		{Account: "333333333333"},
+	}
+	if !reflect.DeepEqual(r.AccountTotals, want) {
+		t.Errorf("AccountTotals = %+v, want %+v", r.AccountTotals, want)
+	}
+	if r.Totals.ENIs.Count != 2 || r.Totals.LoadBalancers.Count != 2 {
+		t.Errorf("Totals = %+v, want 2 ENIs and 2 load balancer IPs", r.Totals)
+	}
+	if r.ProfileTotals != nil {
+		t.Errorf("ProfileTotals = %+v, want none without profiles", r.ProfileTotals)
+	}
+}
+
+func TestCollectAllKeepsPartialResults(t *testing.T) {
+	c := collector.New(&deniedClients{ec2: &deniedEC2{networkInterfaces: []types.NetworkInterface{{
+		NetworkInterfaceId: aws.String("eni-1"),
+		Association:        &types.NetworkInterfaceAssociation{PublicIp: aws.String("1.1.1.1")},
+	}}}})
+	c.Account = "111111111111"
+
+	r, err := CollectAll(context.Background(), []*collector.Collector{c}, collector.RegionSelection{})
+
+	var accountErr *AccountError
+	if !errors.As(err, &accountErr) || accountErr.Account != "111111111111" || !errors.Is(err, errAccessDenied) {
+		t.Errorf("CollectAll() error = %v, want an *AccountError for 111111111111", err)
+	}
+	if r == nil {
+		t.Fatal("CollectAll() report = nil, want the resources collected before the errors")
+	}
+	if len(r.ENIs) != 1 || r.ENIs[0].ENIID != "eni-1" {
+		t.Errorf("ENIs = %+v, want eni-1", r.ENIs)
+	}
+	if len(r.AccountTotals) != 1 || r.AccountTotals[0].ENIs.Count != 1 {
+		t.Errorf("AccountTotals = %+v, want 1 ENI IP for 111111111111", r.AccountTotals)
+	}
+	// The denied Global Accelerator and database calls are warnings rather
+	// than errors
+	warnings := strings.Join(r.Warnings, "\n")
+	for _, service := range []string{"accelerators", "RDS", "Redshift", "OpenSearch"} {
+		if !strings.Contains(warnings, service) || strings.Contains(err.Error(), service) {
+			t.Errorf("Warnings = %q, error = %v, want the %s errors only as warnings", r.Warnings, err, service)
+		}
+	}
+}
+
+func TestInstanceEIPsCountedWithInstances(t *testing.T) {
+	r := &Report{
+		EC2Instances: []collector.EC2InstanceInfo{{Account: "111111111111", InstanceID: "i-1", PublicIP: "1.1.1.1",
+			PublicIPs: []string{"1.1.1.1"}, IPCount: 1, Cost: 3.65}},
+		EIPs: []collector.EIPInfo{
+			{Account: "111111111111", PublicIP: "1.1.1.1", AssociationTarget: "Instance: i-1", Associated: true, InstanceID: "i-1", Cost: 3.65},
+			{Account: "111111111111", PublicIP: "2.2.2.2", AssociationTarget: "NAT Gateway: nat-1", Associated: true, Cost: 3.65},
+		},
+	}
+
+	r.finalize(pricing.Default(), scope{account: "111111111111"})
+
+	if r.Totals.EIPs != total(1, 3.65) {
+		t.Errorf("Totals.EIPs = %+v, want only the EIP not attached to an instance", r.Totals.EIPs)
+	}
+	if r.Totals.Total.Count != 2 {
+		t.Errorf("Totals.Total.Count = %d, want 2", r.Totals.Total.Count)
+	}
+}
+
+func TestInstanceTotalsCountChargedIPs(t *testing.T) {
+	r := &Report{
+		EC2Instances: []collector.EC2InstanceInfo{{Account: "111111111111", InstanceID: "i-1", PublicIP: "1.1.1.1",
+			PublicIPs: []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"}, IPCount: 3, UnchargedIPCount: 1, Cost: 7.3}},
+	}
+
+	r.finalize(pricing.Default(), scope{account: "111111111111"})
+
+	if r.Totals.EC2Instances != total(2, 7.3) {
+		t.Errorf("Totals.EC2Instances = %+v, want the 2 charged IPs of the instance", r.Totals.EC2Instances)
+	}
+}
+
+func total(count int, cost float64) CategoryTotal {
+	var t CategoryTotal
+	t.add(count, cost)
+	return t
+}
+
+func TestProfileTotals(t *testing.T) {
+	r := &Report{
+		ENIs: []collector.ENIInfo{
+			{Profile: "dev", Account: "111111111111", PublicIP: "1.1.1.1", Cost: 3.65},
+			{Profile: "dev", Account: "222222222222", PublicIP: "2.2.2.2", Cost: 3.65},
+			{Profile: "prod", Account: "111111111111", PublicIP: "3.3.3.3", Cost: 3.65},
+		},
+	}
+
+	r.finalize(pricing.Default(), scope{"dev", "111111111111"}, scope{"dev", "222222222222"}, scope{"prod", "111111111111"})
+
+	wantAccounts := []AccountTotals{
+		{Profile: "dev", Account: "111111111111", Totals: Totals{Total: total(1, 3.65), InUse: total(1, 3.65), ENIs: total(1, 3.65)}},
+		{Profile: "dev", Account: "222222222222", Totals: Totals{Total: total(1, 3.65), InUse: total(1, 3.65), ENIs: total(1, 3.65)}},
+		{Profile: "prod", Account: "111111111111", Totals: Totals{Total: total(1, 3.65), InUse: total(1, 3.65), ENIs: total(1, 3.65)}},
+	}
+	if !reflect.DeepEqual(r.AccountTotals, wantAccounts) {
+		t.Errorf("AccountTotals = %+v, want %+v", r.AccountTotals, wantAccounts)
+	}
+
+	wantProfiles := []ProfileTotals{
+		{Profile: "dev", Totals: Totals{Total: total(2, 7.3), InUse: total(2, 7.3), ENIs: total(2, 7.3)}},
+		{Profile: "prod", Totals: Totals{Total: total(1, 3.65), InUse: total(1, 3.65), ENIs: total(1, 3.65)}},
+	}
+	if !reflect.DeepEqual(r.ProfileTotals, wantProfiles) {
+		t.Errorf("ProfileTotals = %+v, want %+v", r.ProfileTotals, wantProfiles)
+	}
+}
+
+func TestServiceTotals(t *testing.T) {
+	r := &Report{
+		ENIs: []collector.ENIInfo{
+			{PublicIP: "1.1.1.1", Service: collector.ServiceNATGateway, Cost: 3.65},
+			{PublicIP: "2.2.2.2", Service: collector.ServiceECS, Cost: 3.65},
+			{PublicIP: "3.3.3.3", Service: collector.ServiceECS, Cost: 3.65},
+		},
+	}
+
+	r.finalize(pricing.Default())
+
+	want := []ServiceTotals{
+		{Service: collector.ServiceECS, CategoryTotal: total(2, 7.3)},
+		{Service: collector.ServiceNATGateway, CategoryTotal: total(1, 3.65)},
+	}
+	if !reflect.DeepEqual(r.ServiceTotals, want) {
+		t.Errorf("ServiceTotals = %+v, want %+v", r.ServiceTotals, want)
+	}
+}
+
+func TestFreeTierCredit(t *testing.T) {
+	r := &Report{
+		ENIs: []collector.ENIInfo{
+			{Account: "111111111111", PublicIP: "1.1.1.1", Cost: 3.65},
+			{Account: "111111111111", PublicIP: "1.1.1.2", Cost: 3.65},
+			{Account: "222222222222", Region: "eu-central-1", PublicIP: "2.2.2.2", Cost: 2.92},
+			{Account: "333333333333", PublicIP: "3.3.3.3", IPOwner: pricing.IPOwnerBYOIP},
+		},
+	}
+
+	model := pricing.Default()
+	model.FreeTierHours = pricing.FreeTierHours
+	model.RegionHourlyRates = map[string]float64{"eu-central-1": 0.004}
+	r.finalize(model, scope{account: "111111111111"}, scope{account: "222222222222"}, scope{account: "333333333333"})
+
+	// 750 hours for the account with 2 IPs, the 730 hours of the single IP
+	// of the other account at its regional rate, and nothing for the free
+	// BYOIP address
+	if want := 3.75 + 2.92; r.Totals.FreeTierCredit < want-1e-9 || r.Totals.FreeTierCredit > want+1e-9 {
+		t.Errorf("FreeTierCredit = %v, want %v", r.Totals.FreeTierCredit, want)
+	}
+	for _, totals := range r.AccountTotals {
+		if totals.Account == "333333333333" && totals.FreeTierCredit != 0 {
+			t.Errorf("FreeTierCredit of the BYOIP account = %v, want 0", totals.FreeTierCredit)
+		}
+	}
+	if r.Currency != pricing.DefaultCurrency {
+		t.Errorf("Currency = %s, want %s", r.Currency, pricing.DefaultCurrency)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (C) 2023 Cristian Magherusan-Stanciu. All rights reserved.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the Open Software License version 3.0 as published
+ * by the Open Source Initiative.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * Open Software License version 3.0 for more details.
+ *
+ * You should have received a copy of the Open Software License version 3.0
+ * along with this program. If not, see <https://opensource.org/licenses/OSL-3.0>.
+ */
+
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Built-in Excel number format for "0.00"
+const xlsxCostNumberFormat = 2
+
+// WriteXLSXFile writes a workbook with a summary sheet followed by one sheet
+// per UI tab, with frozen header rows and numeric cost columns.
+func (r *Report) WriteXLSXFile(outputFile string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	costStyle, err := f.NewStyle(&excelize.Style{NumFmt: xlsxCostNumberFormat})
+	if err != nil {
+		return fmt.Errorf("failed to create cost style: %w", err)
+	}
+	headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return fmt.Errorf("failed to create header style: %w", err)
+	}
+
+	// Show the summary first, as the first sheet a reader would look at
+	var tables []Table
+	for _, table := range r.Tables() {
+		if table.Name == "totals" {
+			table.Rows = append(table.Rows, []interface{}{}, []interface{}{TotalsNote},
+				[]interface{}{fmt.Sprintf("All the costs are in %s, and monthly unless stated otherwise", r.Currency)})
+			tables = append([]Table{table}, tables...)
+			continue
+		}
+		tables = append(tables, table)
+	}
+
+	for i, table := range tables {
+		if i == 0 {
+			err = f.SetSheetName("Sheet1", table.Title)
+		} else {
+			_, err = f.NewSheet(table.Title)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create sheet %s: %w", table.Title, err)
+		}
+
+		if err := writeXLSXSheet(f, table, headerStyle, costStyle); err != nil {
+			return fmt.Errorf("failed to write sheet %s: %w", table.Title, err)
+		}
+	}
+	f.SetActiveSheet(0)
+
+	if err := f.SaveAs(outputFile); err != nil {
+		return fmt.Errorf("failed to save %s: %w", outputFile, err)
+	}
+	return nil
+}
+
+func writeXLSXSheet(f *excelize.File, table Table, headerStyle, costStyle int) error {
+	if err := f.SetSheetRow(table.Title, "A1", &table.Headers); err != nil {
+		return err
+	}
+	lastHeader, err := excelize.CoordinatesToCellName(len(table.Headers), 1)
+	if err != nil {
+		return err
+	}
+	if err := f.SetCellStyle(table.Title, "A1", lastHeader, headerStyle); err != nil {
+		return err
+	}
+
+	for i, row := range table.Rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		if err := f.SetSheetRow(table.Title, cell, &row); err != nil {
+			return err
+		}
+	}
+
+	for i, header := range table.Headers {
+		column, err := excelize.ColumnNumberToName(i + 1)
+		if err != nil {
+			return err
+		}
+		if err := f.SetColWidth(table.Title, column, column, float64(len(header)+4)); err != nil {
+			return err
+		}
+		if !strings.Contains(header, "Cost") || len(table.Rows) == 0 {
+			continue
+		}
+		lastCell, err := excelize.CoordinatesToCellName(i+1, len(table.Rows)+1)
+		if err != nil {
+			return err
+		}
+		if err := f.SetCellStyle(table.Title, column+"2", lastCell, costStyle); err != nil {
+			return err
+		}
+	}
+
+	return f.SetPanes(table.Title, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	})
+}
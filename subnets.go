@@ -21,78 +21,186 @@ import (
 	"fmt"
 	"log"
 
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/ec2"
-	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/gdamore/tcell/v2"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/collector"
 	"github.com/rivo/tview"
 )
 
-func handleSubnets() {
-	// Initialize AWS SDK
-	cfg, err := config.LoadDefaultConfig(context.TODO())
-	if err != nil {
-		log.Fatalf("Unable to load SDK config, %v", err)
-	}
-	client := ec2.NewFromConfig(cfg)
+const confirmPageName = "confirm"
+
+// SubnetChange describes a single MapPublicIpOnLaunch toggle, either applied
+// or only previewed when running with --dry-run.
+type SubnetChange struct {
+	Subnet   collector.SubnetInfo
+	NewValue bool
+}
+
+func (c SubnetChange) String() string {
+	return fmt.Sprintf("%s %s %s: Auto-Attach IP %v -> %v",
+		c.Subnet.Region, c.Subnet.VPCID, c.Subnet.SubnetID, c.Subnet.MapPublicIPOnLaunch, c.NewValue)
+}
+
+// handleSubnets lists the subnets from the selected regions of the accounts
+// loaded like for the main view. It never changes anything unless the user
+// explicitly toggles a subnet and confirms it, and with dryRun set the
+// confirmed changes are only printed on exit.
+func handleSubnets(collectors []*collector.Collector, dryRun bool, selection collector.RegionSelection) {
+	ctx := context.Background()
 
-	// Fetch all regions
-	regions, err := client.DescribeRegions(context.TODO(), &ec2.DescribeRegionsInput{})
-	if err != nil {
-		log.Fatalf("Failed to describe regions, %v", err)
+	// The collector of each subnet, to change it in its own account
+	var subnets []collector.SubnetInfo
+	var owners []*collector.Collector
+	for _, c := range collectors {
+		regions, err := c.SelectRegions(ctx, selection)
+		if err != nil {
+			log.Fatalf("Failed to describe regions of account %s, %v", c.Account, err)
+		}
+		for _, skipped := range regions.Skipped {
+			log.Printf("Skipped region %s in account %s: %s", skipped.Region, c.Account, skipped.Reason)
+		}
+
+		accountSubnets, err := c.FetchAllSubnets(ctx, regions.Selected)
+		if err != nil {
+			log.Printf("Failed to describe subnets, %v", err)
+		}
+		for range accountSubnets {
+			owners = append(owners, c)
+		}
+		subnets = append(subnets, accountSubnets...)
 	}
 
 	// Create a tview table for display
-	table := tview.NewTable().SetBorders(true)
-	table.SetTitle("VPC Subnets").SetBorder(true)
-
-	// Headers
-	table.SetCell(0, 0, tview.NewTableCell("Region"))
-	table.SetCell(0, 1, tview.NewTableCell("VPC ID"))
-	table.SetCell(0, 2, tview.NewTableCell("Subnet ID"))
-	table.SetCell(0, 3, tview.NewTableCell("Auto-Attach IP"))
-
-	row := 1
-	for _, region := range regions.Regions {
-		// Create a regional client
-		regionalClient := ec2.NewFromConfig(cfg, func(o *ec2.Options) {
-			o.Region = *region.RegionName
-		})
-
-		// Fetch subnets in the region
-		resp, err := regionalClient.DescribeSubnets(context.TODO(), &ec2.DescribeSubnetsInput{})
-		if err != nil {
-			log.Printf("Failed to describe subnets in region %s, %v", *region.RegionName, err)
-			continue
+	table := setupTable("VPC Subnets")
+	setTableHeaders(table, "Account", "Region", "VPC ID", "Subnet ID", "Auto-Attach IP")
+
+	for i, subnet := range subnets {
+		table.SetCell(i+1, 0, tview.NewTableCell(accountLabel(subnet.Profile, subnet.Account)))
+		table.SetCell(i+1, 1, tview.NewTableCell(subnet.Region))
+		table.SetCell(i+1, 2, tview.NewTableCell(subnet.VPCID))
+		table.SetCell(i+1, 3, tview.NewTableCell(subnet.SubnetID))
+		table.SetCell(i+1, 4, tview.NewTableCell(fmt.Sprintf("%v", subnet.MapPublicIPOnLaunch)))
+	}
+
+	status := tview.NewTextView()
+	shortcuts := "Use arrows to move around | Press t to toggle Auto-Attach IP | Press ESC to exit"
+	if dryRun {
+		shortcuts += " | Dry run: no changes will be made"
+	}
+
+	flex := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(table, 0, 1, true).
+		AddItem(status, 1, 0, false).
+		AddItem(tview.NewTextView().SetText(shortcuts), 1, 0, false)
+
+	app := tview.NewApplication()
+	pages := tview.NewPages().AddPage("subnets", flex, true, true)
+
+	var changes []SubnetChange
+
+	confirmToggle := func(row int) {
+		if row < 1 || row > len(subnets) {
+			return
 		}
+		change := SubnetChange{Subnet: subnets[row-1], NewValue: !subnets[row-1].MapPublicIPOnLaunch}
+
+		modal := tview.NewModal().
+			SetText(fmt.Sprintf("Toggle Auto-Attach IP for subnet %s in %s?\n\n%v -> %v",
+				change.Subnet.SubnetID, change.Subnet.Region, change.Subnet.MapPublicIPOnLaunch, change.NewValue)).
+			AddButtons([]string{"Cancel", "Toggle"}).
+			SetDoneFunc(func(_ int, buttonLabel string) {
+				pages.RemovePage(confirmPageName)
+				app.SetFocus(table)
+				if buttonLabel != "Toggle" {
+					return
+				}
 
-		// Populate the table with subnet data
-		for _, subnet := range resp.Subnets {
-			table.SetCell(row, 0, tview.NewTableCell(*region.RegionName))
-			table.SetCell(row, 1, tview.NewTableCell(*subnet.VpcId))
-			table.SetCell(row, 2, tview.NewTableCell(*subnet.SubnetId))
-			table.SetCell(row, 3, tview.NewTableCell(fmt.Sprintf("%v", *subnet.MapPublicIpOnLaunch)))
-
-			// Toggle the MapPublicIpOnLaunch attribute
-			newValue := !*subnet.MapPublicIpOnLaunch
-			_, err := regionalClient.ModifySubnetAttribute(context.TODO(), &ec2.ModifySubnetAttributeInput{
-				SubnetId: subnet.SubnetId,
-				MapPublicIpOnLaunch: &types.AttributeBooleanValue{
-					Value: &newValue,
-				},
+				if !dryRun {
+					if err := owners[row-1].SetMapPublicIPOnLaunch(ctx, change.Subnet, change.NewValue); err != nil {
+						log.Print(err)
+						status.SetText(err.Error())
+						return
+					}
+				}
+				// In dry runs the table shows the state the changes would lead to
+				subnets[row-1].MapPublicIPOnLaunch = change.NewValue
+				table.SetCell(row, 4, tview.NewTableCell(fmt.Sprintf("%v", change.NewValue)))
+				changes = append(changes, change)
+				if dryRun {
+					status.SetText("Dry run, would change " + change.String())
+					return
+				}
+				status.SetText("Changed " + change.String())
 			})
-			if err != nil {
-				log.Printf("Failed to toggle Auto-Attach IP for subnet %s, %v", *subnet.SubnetId, err)
+		pages.AddPage(confirmPageName, modal, true, true)
+		app.SetFocus(modal)
+	}
+
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == 't' {
+			row, _ := table.GetSelection()
+			confirmToggle(row)
+			return nil
+		}
+		return handleTableInput(table, event)
+	})
+
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			if pages.HasPage(confirmPageName) {
+				pages.RemovePage(confirmPageName)
+				app.SetFocus(table)
+				return nil
 			}
-			row++
+			app.Stop()
 		}
-	}
+		return event
+	})
 
-	// Create and configure the tview application
-	app := tview.NewApplication()
-	app.SetRoot(table, true).SetFocus(table)
+	app.SetRoot(pages, true).SetFocus(table)
 
 	// Run the tview application
 	if err := app.Run(); err != nil {
 		log.Fatalf("Failed to run application: %v", err)
 	}
+
+	if dryRun {
+		printSubnetChanges(changes)
+	}
+}
+
+// netSubnetChanges collapses the changes of each subnet into a single one,
+// from its original value to the last one, leaving out the subnets toggled
+// back to where they started.
+func netSubnetChanges(changes []SubnetChange) []SubnetChange {
+	var merged []SubnetChange
+	index := map[string]int{}
+	for _, change := range changes {
+		key := change.Subnet.Region + " " + change.Subnet.SubnetID
+		if i, ok := index[key]; ok {
+			merged[i].NewValue = change.NewValue
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, change)
+	}
+
+	var netChanges []SubnetChange
+	for _, change := range merged {
+		if change.NewValue != change.Subnet.MapPublicIPOnLaunch {
+			netChanges = append(netChanges, change)
+		}
+	}
+	return netChanges
+}
+
+func printSubnetChanges(changes []SubnetChange) {
+	changes = netSubnetChanges(changes)
+	if len(changes) == 0 {
+		fmt.Println("Dry run: no subnets would change")
+		return
+	}
+	fmt.Printf("Dry run: %d subnets would change\n", len(changes))
+	for _, change := range changes {
+		fmt.Println(change)
+	}
 }
@@ -17,114 +17,219 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"log"
-	"net"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/ec2"
-	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/gdamore/tcell/v2"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/collector"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/pricing"
+	"github.com/leanercloud/aws-ipv4-cost-viewer/report"
 	"github.com/rivo/tview"
 )
 
+// TimeoutForData is how long to wait for the data of all the accounts
+const TimeoutForData = 5 * time.Minute
+
 const (
-	EIPCostPerHour     = 0.005
-	HoursInMonth       = 720
-	FlatFeePerPublicIP = 3.65
-	TimeoutForEC2      = 20 * time.Second
-	TimeoutForLB       = 20 * time.Second
-	TimeoutForEIP      = 20 * time.Second
-	TimeoutForENI      = 20 * time.Second
+	loadingPageName  = "loading"
+	reportPageName   = "report"
+	regionsPageName  = "regions"
+	warningsPageName = "warnings"
 )
 
-type ChannelData struct {
-	table *tview.Table
-	count int
-	cost  float64
-	err   error
-}
-
-func ipCostsView() error {
-	cfg, err := config.LoadDefaultConfig(context.TODO())
-	if err != nil {
-		return fmt.Errorf("unable to load SDK config: %v", err)
-	}
-
-	ec2Client := ec2.NewFromConfig(cfg)
-	regions, err := fetchRegions(ec2Client)
-	if err != nil {
-		log.Fatalf("Failed to fetch regions: %v", err)
-	}
+func ipCostsView(collectors []*collector.Collector, selection collector.RegionSelection) error {
+	app := tview.NewApplication()
+	pages := tview.NewPages().AddPage(loadingPageName, createLoadingView(), true, true)
+	app.SetRoot(pages, true)
+
+	// Only accessed from the UI goroutine
+	var current *report.Report
+	var eipsTable *tview.Table
+	loading := true
+	showAllEIPs := false
+	// fetchErr is set when nothing could be collected, to return it once the
+	// terminal is restored
+	var fetchErr error
+
+	load := func(selection collector.RegionSelection) {
+		loading = true
+		pages.SwitchToPage(loadingPageName)
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), TimeoutForData)
+			defer cancel()
+
+			debug.Println("Starting data fetch...")
+			startTime := time.Now()
+			r, err := report.CollectAll(ctx, collectors, selection)
+			debug.Printf("Data fetch completed in %v seconds", time.Since(startTime).Seconds())
+			if r == nil {
+				app.QueueUpdate(func() {
+					fetchErr = fmt.Errorf("error fetching data: %w", err)
+					app.Stop()
+				})
+				return
+			}
 
-	ec2Ch, lbCh, eipCh, eniCh := make(chan ChannelData), make(chan ChannelData), make(chan ChannelData), make(chan ChannelData)
+			// Logging would be written over the UI, so the errors are shown
+			// in a dialog instead
+			var messages []string
+			if err != nil {
+				messages = append(messages, fmt.Sprintf("Error fetching some of the data, showing what was collected: %v", err))
+			}
+			for _, warning := range r.Warnings {
+				messages = append(messages, "Warning: "+warning)
+			}
 
-	go func() {
-		defer close(ec2Ch)
-		fetchTableData(createAndPopulateInstancesTable, cfg, regions, ec2Ch)
-		debug.Printf("Finished fetching instances table data")
-	}()
+			eips := createAndPopulateEIPsTable(r.EIPs)
+			tables := []tview.Primitive{
+				createPublicIPsTable(r.IPs),
+				createAndPopulateENIsTable(r.ENIs),
+				createAndPopulateInstancesTable(r.EC2Instances),
+				createAndPopulateLBTable(r.LoadBalancers, r.Lookback),
+				eips,
+				createNATGatewaysTable(r.NATGateways, r.Lookback),
+				createGlobalAcceleratorsTable(r.GlobalAccelerators),
+				createVPNConnectionsTable(r.VPNConnections),
+				createClientVPNEndpointsTable(r.ClientVPNEndpoints),
+				createDatabasesTable(r.Databases),
+				createSummaryView(r),
+				createAccountsTable(r.AccountTotals),
+				createProfilesTable(r.ProfileTotals),
+				createServicesTable(r.ServiceTotals),
+				createScanStatsTable(r.ScanStats),
+				createSkippedRegionsTable(r.SkippedRegions),
+			}
 
-	go func() {
-		defer close(lbCh)
-		fetchTableData(createAndPopulateLBTable, cfg, regions, lbCh)
-		debug.Printf("Finished fetching LB table data")
-	}()
+			tabs, tabNames := createTabs(tables)
+			flex, _ := createMainLayout(tabs, tabNames, r)
+
+			app.QueueUpdateDraw(func() {
+				current = r
+				eipsTable = eips
+				if showAllEIPs {
+					populateEIPsTable(eipsTable, r.EIPs, true)
+				}
+				loading = false
+				pages.AddAndSwitchToPage(reportPageName, flex, true)
+				app.SetFocus(tabs)
+
+				if len(messages) > 0 {
+					modal := tview.NewModal().
+						SetText(strings.Join(messages, "\n")).
+						AddButtons([]string{"OK"}).
+						SetDoneFunc(func(int, string) {
+							pages.RemovePage(warningsPageName)
+							app.SetFocus(tabs)
+						})
+					pages.AddPage(warningsPageName, modal, true, true)
+					app.SetFocus(modal)
+				}
+			})
+		}()
+	}
 
-	go func() {
-		defer close(eipCh)
-		fetchTableData(createAndPopulateEIPsTable, cfg, regions, eipCh)
-		debug.Printf("Finished fetching EIPs table data")
-	}()
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape:
+			if pages.HasPage(regionsPageName) {
+				pages.RemovePage(regionsPageName)
+				return nil
+			}
+			if pages.HasPage(warningsPageName) {
+				pages.RemovePage(warningsPageName)
+				return nil
+			}
+			app.Stop()
+		case event.Key() == tcell.KeyRune && event.Rune() == 'r' && !loading && !pages.HasPage(regionsPageName) && !pages.HasPage(warningsPageName):
+			picker := createRegionPicker(current.EnabledRegions, current.Regions, func(regions []string) {
+				pages.RemovePage(regionsPageName)
+				load(collector.RegionSelection{Include: regions})
+			})
+			pages.AddPage(regionsPageName, picker, true, true)
+			app.SetFocus(picker)
+			return nil
+		case event.Key() == tcell.KeyRune && event.Rune() == 'a' && !loading && !pages.HasPage(regionsPageName) && !pages.HasPage(warningsPageName):
+			showAllEIPs = !showAllEIPs
+			populateEIPsTable(eipsTable, current.EIPs, showAllEIPs)
+			return nil
+		}
+		return event
+	})
 
-	go func() {
-		defer close(eniCh)
-		fetchTableData(createAndPopulateENIsTable, cfg, regions, eniCh)
-		debug.Printf("Finished fetching ENIs table data")
-	}()
+	load(selection)
 
-	err = runUI(ec2Ch, lbCh, eipCh, eniCh)
-	if err != nil {
-		return err
+	if err := app.Run(); err != nil {
+		return fmt.Errorf("failed to run application: %v", err)
 	}
 
-	return nil
+	return fetchErr
 }
 
-func fetchTableData(fetchFunc func(aws.Config, []types.Region) (*tview.Table, int, float64, error),
-	cfg aws.Config,
-	regions []types.Region,
-	ch chan ChannelData) {
+// createRegionPicker lists the enabled regions, with the selected ones checked.
+// Enter toggles a region, and onDone is called with the checked regions from
+// the first item, unless none of them is checked.
+func createRegionPicker(enabled, selected []string, onDone func(regions []string)) *tview.List {
+	checked := map[string]bool{}
+	for _, region := range selected {
+		checked[region] = true
+	}
 
-	debug.Println("Starting data fetch...")
-	startTime := time.Now()
-	table, count, cost, err := fetchFunc(cfg, regions)
-	debug.Printf("Data fetch completed in %v seconds", time.Since(startTime).Seconds())
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetTitle("Regions to scan").SetBorder(true)
 
-	if err != nil {
-		log.Printf("Error fetching table data: %v", err)
-		ch <- ChannelData{nil, 0, 0, err}
-		return
+	itemText := func(region string) string {
+		if checked[region] {
+			return "[x] " + region
+		}
+		return "[ ] " + region
 	}
-	ch <- ChannelData{table, count, cost, nil}
+
+	list.AddItem("Scan the checked regions", "", 0, func() {
+		var regions []string
+		for _, region := range enabled {
+			if checked[region] {
+				regions = append(regions, region)
+			}
+		}
+		if len(regions) > 0 {
+			onDone(regions)
+		}
+	})
+	for i, region := range enabled {
+		index, region := i+1, region
+		list.AddItem(itemText(region), "", 0, func() {
+			checked[region] = !checked[region]
+			list.SetItemText(index, itemText(region), "")
+		})
+	}
+	return list
 }
 
 func createLoadingView() *tview.TextView {
 	return tview.NewTextView().SetText("Loading...").SetTextAlign(tview.AlignCenter)
 }
 
-func createTabs(tables []*tview.Table) (*tview.Pages, *tview.TextView) {
-	pageOrder := []string{"Elastic Network Interfaces (also include EC2, LBs amd EIPs)",
-		"EC2 Instances (includes attached EIPs)",
+func createTabs(tables []tview.Primitive) (*tview.Pages, *tview.TextView) {
+	pageOrder := []string{"Public IPs",
+		"ENIs",
+		"EC2 Instances",
 		"Load Balancers",
-		"EIPs not attached to instances"}
+		"Elastic IPs",
+		"NAT Gateways",
+		"Global Accelerators",
+		"VPN Connections",
+		"Client VPN Endpoints",
+		"Databases",
+		"Summary",
+		"Accounts",
+		"Profiles",
+		"Services",
+		"Scan details",
+		"Skipped regions"}
 
 	tabs := tview.NewPages()
 	for i, table := range tables {
@@ -167,15 +272,14 @@ func createTabs(tables []*tview.Table) (*tview.Pages, *tview.TextView) {
 	return tabs, tabNames
 }
 
-func createMainLayout(tabs *tview.Pages, tabNames *tview.TextView, counts []int, costs []float64) (*tview.Flex, []*tview.TextView) {
+func createMainLayout(tabs *tview.Pages, tabNames *tview.TextView, r *report.Report) (*tview.Flex, []*tview.TextView) {
+	totals := r.Totals
+	format := func(amount float64) string {
+		return pricing.FormatAmount(r.Currency, amount)
+	}
 	costSummaries := []string{
-		"--------------------------------",
-		fmt.Sprintf("Public IPs attached to %d Elastic Network Intefaces: $%.2f", counts[0], costs[0]),
-		fmt.Sprintf("EC2: $%.2f for %d instances", costs[1], counts[1]),
-		fmt.Sprintf("Load balancers: $%.2f for %d load balancer IPs", costs[2], counts[2]),
-		fmt.Sprintf("and $%.2f for %d Elastic IPs", costs[3], counts[3]),
-		"Note: ENI costs also include those for EC2, LB and EIP. Still, unattached EIPs have an additional cost, so the total IPv4 cost isn't exactly the same as the ENI cost",
-		"--------------------------------",
+		fmt.Sprintf("Total: %d public IPs for %s per month, of which %d idle for %s per month, see the Summary tab for the breakdown",
+			totals.Total.Count, format(totals.Total.Cost), totals.Idle.Count, format(totals.Idle.Cost)),
 	}
 
 	flex := tview.NewFlex().SetDirection(tview.FlexRow).
@@ -189,82 +293,95 @@ func createMainLayout(tabs *tview.Pages, tabNames *tview.TextView, counts []int,
 		costTextViews = append(costTextViews, tv)
 	}
 
-	keyboardShortcuts := tview.NewTextView().SetText("Use arrows to move around | Press ESC to exit")
+	keyboardShortcuts := tview.NewTextView().SetText("Use arrows to move around | Press r to pick the regions | Press a to show or hide the EIPs of instances | Press ESC to exit")
 	flex.AddItem(keyboardShortcuts, 1, 0, false)
 
 	return flex, costTextViews
 }
 
-func runUI(ec2Ch, lbCh, eipCh, eniCh chan ChannelData) error {
-	app := tview.NewApplication()
-	loadingView := createLoadingView()
-	app.SetRoot(loadingView, true)
-
-	go func() {
-		tables, counts, costs, err := unpackChannelData(ec2Ch, lbCh, eipCh, eniCh)
-		if err != nil {
-			log.Fatalf("Error fetching data: %v", err)
-		}
-
-		tabs, tabNames := createTabs(tables)
-		flex, _ := createMainLayout(tabs, tabNames, counts, costs)
-
-		app.QueueUpdateDraw(func() {
-			app.SetRoot(flex, true).SetFocus(tabs)
-		})
-	}()
-
-	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyEscape {
-			app.Stop()
-		}
-		return event
-	})
-
-	if err := app.Run(); err != nil {
-		return fmt.Errorf("failed to run application: %v", err)
+// createSummaryView shows the costs of each category, followed by the savings
+// found and the regions scanned, which are too many to fit below the tabs.
+func createSummaryView(r *report.Report) *tview.Flex {
+	totals := r.Totals
+	format := func(amount float64) string {
+		return pricing.FormatAmount(r.Currency, amount)
 	}
 
-	return nil
-}
+	table := setupTable("Cost summary")
+	setTableHeaders(table, "Category", "Count", "Cost", "Hourly Cost", "Annual Cost")
 
-func unpackChannelData(ec2Ch, lbCh, eipCh, eniCh chan ChannelData) ([]*tview.Table, []int, []float64, error) {
-	log.Println("Unpacking channel data...")
-
-	var eniData, ec2Data, lbData, eipData ChannelData
-
-	channels := []struct {
-		ch      chan ChannelData
-		data    *ChannelData
-		timeout time.Duration
+	row := 1
+	for _, category := range []struct {
+		name  string
+		total report.CategoryTotal
 	}{
-		{eniCh, &eniData, TimeoutForENI},
-		{ec2Ch, &ec2Data, TimeoutForEC2},
-		{lbCh, &lbData, TimeoutForLB},
-		{eipCh, &eipData, TimeoutForEIP},
+		{"Total public IPs", totals.Total},
+		{"In use", totals.InUse},
+		{"Idle", totals.Idle},
+		{"ENI public IPs", totals.ENIs},
+		{"EC2 instances", totals.EC2Instances},
+		{"Load balancer IPs", totals.LoadBalancers},
+		{"Elastic IPs not attached to instances", totals.EIPs},
+		{"Global Accelerator static IPs", totals.GlobalAccelerators},
+		{"VPN tunnel and Client VPN IPs", totals.VPNs},
+		{"RDS, Redshift and OpenSearch IPs", totals.Databases},
+	} {
+		table.SetCell(row, 0, tview.NewTableCell(category.name))
+		table.SetCell(row, 1, tview.NewTableCell(strconv.Itoa(category.total.Count)))
+		table.SetCell(row, 2, tview.NewTableCell(format(category.total.Cost)))
+		table.SetCell(row, 3, tview.NewTableCell(format(category.total.HourlyCost)))
+		table.SetCell(row, 4, tview.NewTableCell(format(category.total.AnnualCost)))
+		row++
 	}
 
-	for _, ch := range channels {
-		select {
-		case data, ok := <-ch.ch:
-			if !ok {
-				return nil, nil, nil, fmt.Errorf("channel was closed before data was received")
-			}
-			*ch.data = data
-		case <-time.After(ch.timeout):
-			return nil, nil, nil, fmt.Errorf("timeout waiting for data from %v channel", ch.ch)
+	var notes []string
+	if totals.FreeTierCredit > 0 {
+		notes = append(notes, fmt.Sprintf("Free Tier: %s per month covered, leaving %s in total",
+			format(totals.FreeTierCredit), format(totals.Total.Cost-totals.FreeTierCredit)))
+	}
+	var candidates int
+	var candidatesCost float64
+	for _, lb := range r.LoadBalancers {
+		if lb.DualStackCandidate {
+			candidates++
+			candidatesCost += lb.Cost
 		}
-
-		if ch.data.err != nil {
-			return nil, nil, nil, ch.data.err
+	}
+	if candidates > 0 {
+		notes = append(notes, fmt.Sprintf("%d load balancers could switch to dualstack-without-public-ipv4, saving %s per month",
+			candidates, format(candidatesCost)))
+	}
+	var natCandidates int
+	for _, natGateway := range r.NATGateways {
+		if natGateway.ConsolidationCandidate {
+			natCandidates++
 		}
 	}
-
-	tables := []*tview.Table{eniData.table, ec2Data.table, lbData.table, eipData.table}
-	counts := []int{eniData.count, ec2Data.count, lbData.count, eipData.count}
-	costs := []float64{eniData.cost, ec2Data.cost, lbData.cost, eipData.cost}
-
-	return tables, counts, costs, nil
+	if natCandidates > 0 {
+		notes = append(notes, fmt.Sprintf("%d NAT gateways with hardly any traffic could be consolidated, see the NAT Gateways tab", natCandidates))
+	}
+	var databaseCandidates int
+	var databaseCandidatesCost float64
+	for _, database := range r.Databases {
+		if database.PrivateCandidate {
+			databaseCandidates++
+			databaseCandidatesCost += database.Cost
+		}
+	}
+	if databaseCandidates > 0 {
+		notes = append(notes, fmt.Sprintf("%d databases unreachable from the internet could be made private, saving %s per month",
+			databaseCandidates, format(databaseCandidatesCost)))
+	}
+	notes = append(notes,
+		fmt.Sprintf("Scanned %d regions, skipped %d", len(r.Regions), len(r.SkippedRegions)),
+		report.TotalsNote,
+	)
+
+	// Each of the rows and the borders around them take a line
+	notesView := tview.NewTextView().SetText(strings.Join(notes, "\n"))
+	return tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(table, 2*row+3, 0, true).
+		AddItem(notesView, 0, 1, false)
 }
 
 // Helper function to set up the table
@@ -302,174 +419,392 @@ func setTableHeaders(table *tview.Table, headers ...string) {
 	}
 }
 
-func createAndPopulateInstancesTable(config aws.Config, regions []types.Region) (*tview.Table, int, float64, error) {
+func createAndPopulateInstancesTable(instances []collector.EC2InstanceInfo) *tview.Table {
 	debug.Println("Starting createAndPopulateInstancesTable...")
 
-	table := setupTable("EC2 Instances costs")
-	debug.Println("Table setup done.")
-
-	setTableHeaders(table, "Region", "Name Tag", "Instance State", "Instance ID", "Public IP", "VPC ID", "Subnet ID", "Cost")
-	debug.Println("Table headers set.")
-
-	debug.Println("Fetching all EC2 instances...")
-	allInstances, err := fetchAllInstances(config, regions)
-	if err != nil {
-		debug.Printf("Error fetching all EC2 instances: %v", err)
-		return nil, 0, 0, err
-	}
-	debug.Printf("Fetched %d EC2 instances.", len(allInstances))
-
-	debug.Println("Sorting instances by IP...")
-	sortStructsByIP(allInstances, func(i int) string {
-		return allInstances[i].PublicIP
-	})
-	debug.Println("Sorting done.")
+	table := setupTable("EC2 Instances costs, including their attached EIPs")
+	setTableHeaders(table, "Account", "Region", "Name Tag", "Instance State", "Instance ID", "Public IPs", "IP Count", "VPC ID", "Subnet ID", "Cost")
 
 	debug.Println("Populating table with instance data...")
 	row := 1
-	totalCost := 0.0
-	for _, instanceInfo := range allInstances {
-		table.SetCell(row, 0, tview.NewTableCell(instanceInfo.Region))
-		table.SetCell(row, 1, tview.NewTableCell(instanceInfo.NameTag))
-		table.SetCell(row, 2, tview.NewTableCell(instanceInfo.InstanceState))
-		table.SetCell(row, 3, tview.NewTableCell(instanceInfo.InstanceID))
-		table.SetCell(row, 4, tview.NewTableCell(instanceInfo.PublicIP))
-		table.SetCell(row, 5, tview.NewTableCell(instanceInfo.VPCID))
-		table.SetCell(row, 6, tview.NewTableCell(instanceInfo.SubnetID))
-		table.SetCell(row, 7, tview.NewTableCell(fmt.Sprintf("%.2f", instanceInfo.Cost)))
-		totalCost += instanceInfo.Cost
+	for _, instanceInfo := range instances {
+		table.SetCell(row, 0, tview.NewTableCell(accountLabel(instanceInfo.Profile, instanceInfo.Account)))
+		table.SetCell(row, 1, tview.NewTableCell(instanceInfo.Region))
+		table.SetCell(row, 2, tview.NewTableCell(instanceInfo.NameTag))
+		table.SetCell(row, 3, tview.NewTableCell(instanceInfo.InstanceState))
+		table.SetCell(row, 4, tview.NewTableCell(instanceInfo.InstanceID))
+		table.SetCell(row, 5, tview.NewTableCell(strings.Join(instanceInfo.PublicIPs, " ")))
+		table.SetCell(row, 6, tview.NewTableCell(ipCountLabel(instanceInfo.IPCount, instanceInfo.UnchargedIPCount)))
+		table.SetCell(row, 7, tview.NewTableCell(instanceInfo.VPCID))
+		table.SetCell(row, 8, tview.NewTableCell(instanceInfo.SubnetID))
+		table.SetCell(row, 9, tview.NewTableCell(fmt.Sprintf("%.2f", instanceInfo.Cost)))
 		row++
 	}
-	debug.Println("Instances table population done.")
-
-	debug.Printf("Total Instances IPs cost: $%.2f", totalCost)
 
 	debug.Println("Finished createAndPopulateInstancesTable.")
-	return table, len(allInstances), totalCost, nil
+	return table
 }
 
-func createAndPopulateEIPsTable(config aws.Config, regions []types.Region) (*tview.Table, int, float64, error) {
-	debug.Println("Starting createAndPopulateEIPsTable...")
+func createAndPopulateEIPsTable(eips []collector.EIPInfo) *tview.Table {
+	table := setupTable("")
+	populateEIPsTable(table, eips, false)
+	return table
+}
 
-	table := setupTable("Elastic IPs")
-	setTableHeaders(table, "Region", "Name tag", "Public IP", "Attached Resource", "Cost")
+// populateEIPsTable fills the EIPs table with all the EIPs, or only those not
+// attached to EC2 instances unless showAll is set.
+func populateEIPsTable(table *tview.Table, eips []collector.EIPInfo, showAll bool) {
+	debug.Println("Starting populateEIPsTable...")
 
-	debug.Println("Fetching all EIPs...")
-	allEIPs, err := fetchAllEIPs(config, regions)
-	if err != nil {
-		debug.Printf("Error fetching all EIPs: %v", err)
-		return nil, 0, 0, err
+	table.Clear()
+	if showAll {
+		table.SetTitle("All Elastic IPs")
+	} else {
+		table.SetTitle("Elastic IPs not attached to instances")
 	}
-	debug.Printf("Fetched %d EIPs", len(allEIPs))
-
-	debug.Println("Sorting EIPs by IP...")
-	sortStructsByIP(allEIPs, func(i int) string {
-		return allEIPs[i].PublicIP
-	})
+	setTableHeaders(table, "Account", "Region", "Name tag", "Public IP", "Allocation ID", "Attached Resource", "Private IP", "Public IPv4 Pool", "IP Owner", "Cost")
 
 	row := 1
-
-	totalCost := 0.0
 	debug.Println("Populating table with EIP data...")
-	for _, eipInfo := range allEIPs {
-		table.SetCell(row, 0, tview.NewTableCell(eipInfo.Region))
-		table.SetCell(row, 1, tview.NewTableCell(eipInfo.NameTag))
-		table.SetCell(row, 2, tview.NewTableCell(eipInfo.PublicIP))
-		table.SetCell(row, 3, tview.NewTableCell(eipInfo.AssociationTarget))
-		table.SetCell(row, 4, tview.NewTableCell(fmt.Sprintf("%.2f", eipInfo.Cost)))
-
-		totalCost += eipInfo.Cost
+	for _, eipInfo := range eips {
+		if eipInfo.InstanceID != "" && !showAll {
+			continue
+		}
+		associationTarget := eipInfo.AssociationTarget
+		if !eipInfo.Associated {
+			associationTarget = "Unassociated"
+		}
+		for i, value := range []string{accountLabel(eipInfo.Profile, eipInfo.Account), eipInfo.Region, eipInfo.NameTag, eipInfo.PublicIP,
+			eipInfo.AllocationID, associationTarget, eipInfo.PrivateIP, eipInfo.PublicIPv4Pool, eipInfo.IPOwner, fmt.Sprintf("%.2f", eipInfo.Cost)} {
+			table.SetCell(row, i, tview.NewTableCell(value))
+		}
 		row++
 	}
+	table.Select(0, 0)
 
-	debug.Printf("Finished createAndPopulateEIPsTable. Total EIPs: %d, Total Cost: %f", len(allEIPs), totalCost)
-	return table, len(allEIPs), totalCost, nil
+	debug.Printf("Finished populateEIPsTable. Total EIPs: %d", row-1)
 }
 
-func createAndPopulateENIsTable(config aws.Config, regions []types.Region) (*tview.Table, int, float64, error) {
+func createAndPopulateENIsTable(enis []collector.ENIInfo) *tview.Table {
 	debug.Println("Starting createAndPopulateENIsTable...")
 
-	table := setupTable("Elastic Network Interfaces with Public IPs")
-	setTableHeaders(table, "Region", "Public IP", "ENI ID", "Cost")
-
-	debug.Println("Fetching all ENIs...")
-	allENIs, err := fetchAllENIs(config, regions)
-	if err != nil {
-		debug.Printf("Error fetching all ENIs: %v", err)
-		return nil, 0, 0, err
-	}
-	debug.Printf("Fetched %d ENIs", len(allENIs))
-
-	debug.Println("Sorting ENIs by IP...")
-	sortStructsByIP(allENIs, func(i int) string {
-		return allENIs[i].PublicIP
-	})
+	table := setupTable("Elastic Network Interfaces with Public IPs, including those of EC2 instances, load balancers and EIPs")
+	setTableHeaders(table, "Account", "Region", "Public IP", "Private IP", "ENI ID", "Service", "Owner ID", "Description", "IP Owner", "Cost")
 
 	debug.Println("Populating table with ENI data...")
 	row := 1
-	totalCost := 0.0
-	for _, eniInfo := range allENIs {
-		table.SetCell(row, 0, tview.NewTableCell(eniInfo.Region))
-		table.SetCell(row, 1, tview.NewTableCell(eniInfo.PublicIP))
-		table.SetCell(row, 2, tview.NewTableCell(eniInfo.ENIID))
-		table.SetCell(row, 3, tview.NewTableCell(fmt.Sprintf("%.2f", eniInfo.Cost)))
-		totalCost += eniInfo.Cost
+	for _, eniInfo := range enis {
+		table.SetCell(row, 0, tview.NewTableCell(accountLabel(eniInfo.Profile, eniInfo.Account)))
+		table.SetCell(row, 1, tview.NewTableCell(eniInfo.Region))
+		table.SetCell(row, 2, tview.NewTableCell(eniInfo.PublicIP))
+		table.SetCell(row, 3, tview.NewTableCell(eniInfo.PrivateIP))
+		table.SetCell(row, 4, tview.NewTableCell(eniInfo.ENIID))
+		table.SetCell(row, 5, tview.NewTableCell(eniInfo.Service))
+		table.SetCell(row, 6, tview.NewTableCell(eniInfo.OwnerID))
+		table.SetCell(row, 7, tview.NewTableCell(eniInfo.Description))
+		table.SetCell(row, 8, tview.NewTableCell(eniInfo.IPOwner))
+		table.SetCell(row, 9, tview.NewTableCell(fmt.Sprintf("%.2f", eniInfo.Cost)))
 		row++
 	}
 
-	debug.Printf("Finished createAndPopulateENIsTable. Total ENIs: %d, Total Cost: %f", len(allENIs), totalCost)
-	return table, len(allENIs), totalCost, nil
+	debug.Printf("Finished createAndPopulateENIsTable. Total ENIs: %d", len(enis))
+	return table
 }
 
-func createAndPopulateLBTable(cfg aws.Config, regions []types.Region) (*tview.Table, int, float64, error) {
+func createAndPopulateLBTable(lbs []collector.LoadBalancerInfo, lookback string) *tview.Table {
 	debug.Println("Starting createAndPopulateLBTable...")
 
 	table := setupTable("Load balancer costs")
-	setTableHeaders(table, "Region", "Load Balancer Type", "DNS Name", "IP Count", "Traffic MBs (last 7 days)", "Cost")
+	window := fmt.Sprintf(" (last %s)", lookback)
+	setTableHeaders(table, "Account", "Region", "Load Balancer Type", "Scheme", "DNS Name", "IP Address Type", "Dual-Stack Candidate", "IP Source", "IP Count",
+		"Traffic MBs"+window, "Requests"+window, "Peak Connections"+window, "New Connections"+window,
+		"Healthy Hosts", "Targets", "IPv4 Cost per GB", "IPv4 Cost per Million Requests", "Cost")
 
-	debug.Println("Fetching all load balancers...")
-	allLBs, err := fetchAllLoadBalancers(cfg, regions)
-	if err != nil {
-		debug.Printf("Error fetching all load balancers: %v", err)
-		return nil, 0, 0, err
+	row := 1
+	debug.Println("Populating table with load balancer data...")
+	for _, lbInfo := range lbs {
+		for column, cell := range []string{
+			accountLabel(lbInfo.Profile, lbInfo.Account),
+			lbInfo.Region,
+			lbInfo.Type,
+			lbInfo.Scheme,
+			lbInfo.DNSName,
+			lbInfo.IPAddressType,
+			dualStackLabel(lbInfo),
+			lbInfo.IPSource,
+			ipCountLabel(lbInfo.IPCount, lbInfo.UnchargedIPCount),
+			fmt.Sprintf("%.2f", float64(lbInfo.ProcessedBytes)/1024.0/1024.0),
+			strconv.FormatInt(lbInfo.RequestCount, 10),
+			strconv.FormatInt(lbInfo.PeakActiveConnections, 10),
+			strconv.FormatInt(lbInfo.NewConnections, 10),
+			strconv.Itoa(lbInfo.HealthyHosts),
+			strconv.Itoa(lbInfo.Targets),
+			fmt.Sprintf("%.4f", lbInfo.CostPerGB),
+			fmt.Sprintf("%.4f", lbInfo.CostPerMillionRequests),
+			fmt.Sprintf("%.2f", lbInfo.Cost),
+		} {
+			table.SetCell(row, column, tview.NewTableCell(cell))
+		}
+		row++
 	}
-	debug.Printf("Fetched %d load balancers", len(allLBs))
 
-	debug.Println("Sorting load balancers by IP...")
-	sortStructsByIP(allLBs, func(i int) string {
-		if len(allLBs[i].PublicIPs) > 0 {
-			return allLBs[i].PublicIPs[0]
+	debug.Printf("Finished createAndPopulateLBTable. Total load balancers: %d", len(lbs))
+	return table
+}
+
+// ipCountLabel returns an IP count, along with how many of the IPs aren't
+// charged, if any
+func ipCountLabel(ipCount, uncharged int) string {
+	if uncharged > 0 {
+		return fmt.Sprintf("%d (%d uncharged)", ipCount, uncharged)
+	}
+	return strconv.Itoa(ipCount)
+}
+
+func createNATGatewaysTable(natGateways []collector.NATGatewayInfo, lookback string) *tview.Table {
+	table := setupTable("NAT gateways")
+	window := fmt.Sprintf(" (last %s)", lookback)
+	setTableHeaders(table, "Account", "Region", "NAT Gateway ID", "Name Tag", "State", "Connectivity", "VPC ID", "Subnet ID", "AZ", "Public IPs",
+		"Traffic MBs"+window, "Peak Connections"+window, "Consolidation", "Cost")
+
+	for i, natGateway := range natGateways {
+		for column, cell := range []string{
+			accountLabel(natGateway.Profile, natGateway.Account),
+			natGateway.Region,
+			natGateway.NATGatewayID,
+			natGateway.NameTag,
+			natGateway.State,
+			natGateway.ConnectivityType,
+			natGateway.VPCID,
+			natGateway.SubnetID,
+			natGateway.AvailabilityZone,
+			strings.Join(natGateway.PublicIPs, " "),
+			fmt.Sprintf("%.2f", float64(natGateway.BytesOutToDestination)/1024.0/1024.0),
+			strconv.FormatInt(natGateway.PeakActiveConnections, 10),
+			natGateway.ConsolidationNote,
+			fmt.Sprintf("%.2f", natGateway.Cost),
+		} {
+			table.SetCell(i+1, column, tview.NewTableCell(cell))
 		}
-		return "" // Default value if no IPs
-	})
+	}
+	return table
+}
 
-	row := 1
-	totalIPCount := 0
-	totalCost := 0.0
-	debug.Println("Populating table with load balancer data...")
-	for _, lbInfo := range allLBs {
-		table.SetCell(row, 0, tview.NewTableCell(lbInfo.Region))
-		table.SetCell(row, 1, tview.NewTableCell(lbInfo.Type))
-		table.SetCell(row, 2, tview.NewTableCell(lbInfo.DNSName))
-		table.SetCell(row, 3, tview.NewTableCell(strconv.Itoa(lbInfo.IPCount)))
-		table.SetCell(row, 4, tview.NewTableCell(fmt.Sprintf("%.2f", float64(lbInfo.TrafficLastWeek)/1024.0/1024.0)))
-		table.SetCell(row, 5, tview.NewTableCell(fmt.Sprintf("%.2f", lbInfo.Cost)))
-		row++
+func createGlobalAcceleratorsTable(accelerators []collector.GlobalAcceleratorInfo) *tview.Table {
+	table := setupTable("Global Accelerators, charged in " + collector.GlobalAcceleratorRegion)
+	setTableHeaders(table, "Account", "Name", "Type", "Status", "Enabled", "IP Address Type", "DNS Name", "Public IPs", "Listeners",
+		"Endpoint Groups", "Cost")
+
+	for i, accelerator := range accelerators {
+		for column, cell := range []string{
+			accountLabel(accelerator.Profile, accelerator.Account),
+			accelerator.Name,
+			accelerator.Type,
+			accelerator.Status,
+			strconv.FormatBool(accelerator.Enabled),
+			accelerator.IPAddressType,
+			accelerator.DNSName,
+			strings.Join(accelerator.PublicIPs, " "),
+			strings.Join(accelerator.Listeners, "; "),
+			strings.Join(accelerator.EndpointGroups, "; "),
+			fmt.Sprintf("%.2f", accelerator.Cost),
+		} {
+			table.SetCell(i+1, column, tview.NewTableCell(cell))
+		}
+	}
+	return table
+}
 
-		totalIPCount += lbInfo.IPCount
-		totalCost += lbInfo.Cost
+func createVPNConnectionsTable(vpnConnections []collector.VPNConnectionInfo) *tview.Table {
+	table := setupTable("Site-to-Site VPN connections")
+	setTableHeaders(table, "Account", "Region", "VPN Connection ID", "Name Tag", "State", "Gateway", "Customer Gateway ID", "Outside IPs",
+		"Accelerated", "Tunnel Outside IPs", "Cost")
+
+	for i, vpnConnection := range vpnConnections {
+		for column, cell := range []string{
+			accountLabel(vpnConnection.Profile, vpnConnection.Account),
+			vpnConnection.Region,
+			vpnConnection.VPNConnectionID,
+			vpnConnection.NameTag,
+			vpnConnection.State,
+			vpnConnection.Gateway,
+			vpnConnection.CustomerGatewayID,
+			vpnConnection.OutsideIPAddressType,
+			strconv.FormatBool(vpnConnection.Accelerated),
+			strings.Join(vpnConnection.TunnelOutsideIPs, " "),
+			fmt.Sprintf("%.2f", vpnConnection.Cost),
+		} {
+			table.SetCell(i+1, column, tview.NewTableCell(cell))
+		}
 	}
+	return table
+}
 
-	debug.Printf("Finished createAndPopulateLBTable. Total IP Count: %d, Total Cost: %f", totalIPCount, totalCost)
-	return table, totalIPCount, totalCost, nil
+func createClientVPNEndpointsTable(endpoints []collector.ClientVPNEndpointInfo) *tview.Table {
+	table := setupTable("Client VPN endpoints")
+	setTableHeaders(table, "Account", "Region", "Client VPN Endpoint ID", "Name Tag", "Status", "DNS Name", "VPC ID", "Subnet IDs", "ENI IDs",
+		"Public IPs", "Cost")
+
+	for i, endpoint := range endpoints {
+		for column, cell := range []string{
+			accountLabel(endpoint.Profile, endpoint.Account),
+			endpoint.Region,
+			endpoint.ClientVPNEndpointID,
+			endpoint.NameTag,
+			endpoint.Status,
+			endpoint.DNSName,
+			endpoint.VPCID,
+			strings.Join(endpoint.SubnetIDs, " "),
+			strings.Join(endpoint.ENIIDs, " "),
+			strings.Join(endpoint.PublicIPs, " "),
+			fmt.Sprintf("%.2f", endpoint.Cost),
+		} {
+			table.SetCell(i+1, column, tview.NewTableCell(cell))
+		}
+	}
+	return table
 }
 
-func sortStructsByIP(data interface{}, getIP func(i int) string) {
-	sort.Slice(data, func(i, j int) bool {
-		ip1, ip2 := net.ParseIP(getIP(i)), net.ParseIP(getIP(j))
-		if ip1 == nil || ip2 == nil {
-			return false
+func createDatabasesTable(databases []collector.DatabaseInfo) *tview.Table {
+	table := setupTable("Publicly accessible databases")
+	setTableHeaders(table, "Account", "Region", "Service", "ID", "Cluster", "Engine", "Status", "Endpoint", "VPC ID", "ENI IDs", "Public IPs",
+		"Public Ingress Groups", "Make Private", "Cost")
+
+	for i, database := range databases {
+		for column, cell := range []string{
+			accountLabel(database.Profile, database.Account),
+			database.Region,
+			database.Service,
+			database.ID,
+			database.Cluster,
+			database.Engine,
+			database.Status,
+			database.Endpoint,
+			database.VPCID,
+			strings.Join(database.ENIIDs, " "),
+			strings.Join(database.PublicIPs, " "),
+			strings.Join(database.PublicIngressGroups, " "),
+			database.PrivateNote,
+			fmt.Sprintf("%.2f", database.Cost),
+		} {
+			table.SetCell(i+1, column, tview.NewTableCell(cell))
 		}
-		return bytes.Compare(ip1, ip2) < 0
-	})
+	}
+	return table
+}
+
+func createPublicIPsTable(ips []report.PublicIP) *tview.Table {
+	table := setupTable("Public IPs, each counted once")
+	setTableHeaders(table, "Account", "Region", "Public IP", "Status", "Owner Type", "Owner ID", "Owner Name", "ENI ID", "IP Owner", "Sources", "Cost")
+
+	for i, ip := range ips {
+		table.SetCell(i+1, 0, tview.NewTableCell(accountLabel(ip.Profile, ip.Account)))
+		table.SetCell(i+1, 1, tview.NewTableCell(ip.Region))
+		table.SetCell(i+1, 2, tview.NewTableCell(ip.PublicIP))
+		table.SetCell(i+1, 3, tview.NewTableCell(ip.Status))
+		table.SetCell(i+1, 4, tview.NewTableCell(ip.OwnerType))
+		table.SetCell(i+1, 5, tview.NewTableCell(ip.OwnerID))
+		table.SetCell(i+1, 6, tview.NewTableCell(ip.OwnerName))
+		table.SetCell(i+1, 7, tview.NewTableCell(ip.ENIID))
+		table.SetCell(i+1, 8, tview.NewTableCell(ip.IPOwner))
+		table.SetCell(i+1, 9, tview.NewTableCell(strings.Join(ip.Sources, " ")))
+		table.SetCell(i+1, 10, tview.NewTableCell(fmt.Sprintf("%.2f", ip.Cost)))
+	}
+	return table
+}
+
+// dualStackLabel tells whether a load balancer could switch to
+// dualstack-without-public-ipv4, and what it needs for it.
+func dualStackLabel(lb collector.LoadBalancerInfo) string {
+	switch {
+	case !lb.DualStackCandidate:
+		return ""
+	case lb.DualStackNote != "":
+		return "yes, " + lb.DualStackNote
+	default:
+		return "yes"
+	}
+}
+
+func createAccountsTable(accounts []report.AccountTotals) *tview.Table {
+	table := setupTable("Costs per account")
+	setTableHeaders(table, "Account", "Public IPs", "Total Cost", "Idle IPs", "Idle Cost", "ENI IPs", "ENI Cost", "EC2 Instances", "EC2 Cost",
+		"Load Balancer IPs", "Load Balancer Cost", "Elastic IPs", "Elastic IP Cost", "Global Accelerator IPs", "Global Accelerator Cost", "VPN IPs", "VPN Cost",
+		"Database IPs", "Database Cost")
+
+	for i, account := range accounts {
+		setTotalsRow(table, i+1, accountLabel(account.Profile, account.Account), account.Totals)
+	}
+	return table
+}
+
+func createProfilesTable(profiles []report.ProfileTotals) *tview.Table {
+	table := setupTable("Costs per profile")
+	setTableHeaders(table, "Profile", "Public IPs", "Total Cost", "Idle IPs", "Idle Cost", "ENI IPs", "ENI Cost", "EC2 Instances", "EC2 Cost",
+		"Load Balancer IPs", "Load Balancer Cost", "Elastic IPs", "Elastic IP Cost", "Global Accelerator IPs", "Global Accelerator Cost", "VPN IPs", "VPN Cost",
+		"Database IPs", "Database Cost")
+
+	for i, profile := range profiles {
+		setTotalsRow(table, i+1, profile.Profile, profile.Totals)
+	}
+	return table
+}
+
+func createServicesTable(services []report.ServiceTotals) *tview.Table {
+	table := setupTable("Costs of the ENIs per owning service")
+	setTableHeaders(table, "Service", "ENI IPs", "Cost", "Annual Cost")
+
+	for i, service := range services {
+		table.SetCell(i+1, 0, tview.NewTableCell(service.Service))
+		table.SetCell(i+1, 1, tview.NewTableCell(strconv.Itoa(service.Count)))
+		table.SetCell(i+1, 2, tview.NewTableCell(fmt.Sprintf("%.2f", service.Cost)))
+		table.SetCell(i+1, 3, tview.NewTableCell(fmt.Sprintf("%.2f", service.AnnualCost)))
+	}
+	return table
+}
+
+func setTotalsRow(table *tview.Table, row int, name string, totals report.Totals) {
+	cells := []string{name}
+	for _, total := range []report.CategoryTotal{totals.Total, totals.Idle, totals.ENIs, totals.EC2Instances, totals.LoadBalancers, totals.EIPs, totals.GlobalAccelerators, totals.VPNs,
+		totals.Databases} {
+		cells = append(cells, strconv.Itoa(total.Count), fmt.Sprintf("%.2f", total.Cost))
+	}
+	for column, cell := range cells {
+		table.SetCell(row, column, tview.NewTableCell(cell))
+	}
+}
+
+// accountLabel shows the account along with the profile it was scanned
+// through, when profiles are used.
+func accountLabel(profile, account string) string {
+	if profile == "" {
+		return account
+	}
+	return profile + "/" + account
+}
+
+func createSkippedRegionsTable(skipped []collector.SkippedRegion) *tview.Table {
+	table := setupTable("Regions that weren't scanned")
+	setTableHeaders(table, "Account", "Region", "Reason")
+
+	for i, region := range skipped {
+		table.SetCell(i+1, 0, tview.NewTableCell(accountLabel(region.Profile, region.Account)))
+		table.SetCell(i+1, 1, tview.NewTableCell(region.Region))
+		table.SetCell(i+1, 2, tview.NewTableCell(region.Reason))
+	}
+	return table
+}
+
+func createScanStatsTable(stats []collector.ScanStat) *tview.Table {
+	table := setupTable("Pages and items scanned per region")
+	setTableHeaders(table, "Account", "Region", "Operation", "Pages", "Items")
+
+	for i, stat := range stats {
+		table.SetCell(i+1, 0, tview.NewTableCell(accountLabel(stat.Profile, stat.Account)))
+		table.SetCell(i+1, 1, tview.NewTableCell(stat.Region))
+		table.SetCell(i+1, 2, tview.NewTableCell(stat.Operation))
+		table.SetCell(i+1, 3, tview.NewTableCell(strconv.Itoa(stat.Pages)))
+		table.SetCell(i+1, 4, tview.NewTableCell(strconv.Itoa(stat.Items)))
+	}
+	return table
 }